@@ -0,0 +1,48 @@
+// Command jobworker-audit inspects the audit log written by the jobworker
+// server.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/s-gruneberg/jobWorker/internal/audit"
+)
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch flag.Arg(0) {
+	case "verify":
+		if flag.NArg() < 2 {
+			usage()
+			os.Exit(2)
+		}
+		runVerify(flag.Arg(1))
+	default:
+		fmt.Fprintf(os.Stderr, "jobworker-audit: unknown subcommand %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: jobworker-audit verify <path>")
+}
+
+// runVerify walks the audit log at path and exits non-zero if the hash chain
+// is broken anywhere.
+func runVerify(path string) {
+	n, err := audit.Verify(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jobworker-audit: verify failed after %d valid record(s): %v\n", n, err)
+		os.Exit(1)
+	}
+	fmt.Printf("ok: %d record(s) verified\n", n)
+}
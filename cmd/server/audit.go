@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/s-gruneberg/jobWorker/internal/audit"
+	"github.com/s-gruneberg/jobWorker/internal/auth"
+)
+
+// Outcomes recorded for an audited action.
+const (
+	auditResultSuccess = "success"
+	auditResultFailure = "failure"
+	auditResultDenied  = "denied"
+)
+
+// auditLog is the process-wide audit recorder, configured in main() from the
+// --audit-log flag. It is left nil in tests that exercise handlers directly,
+// in which case recordAudit is a no-op.
+var auditLog *audit.Logger
+
+// auditLogPath backs the GET /audit endpoint, which reads the log back from
+// disk rather than holding it in memory.
+var auditLogPath string
+
+// recordAudit appends an audit record for action taken by principal against
+// r. Failures to append are logged to stderr but never surfaced to the
+// caller - auditing must not break the API.
+func recordAudit(r *http.Request, principal auth.Principal, action, jobID, command string, args []string, result, detail string) {
+	if auditLog == nil {
+		return
+	}
+
+	rec := audit.Record{
+		Timestamp:  time.Now(),
+		Principal:  principal.ID,
+		Role:       principal.Role,
+		Action:     action,
+		JobID:      jobID,
+		Command:    command,
+		Args:       args,
+		RemoteAddr: r.RemoteAddr,
+		Result:     result,
+		Detail:     detail,
+	}
+	if _, err := auditLog.Append(rec); err != nil {
+		fmt.Fprintf(os.Stderr, "audit: failed to record %s: %v\n", action, err)
+	}
+}
+
+// handleGetAudit streams the audit log as NDJSON, optionally filtered to
+// records at or after ?since=<RFC3339 timestamp>. Restricted to admins by
+// rolePermissions.
+func handleGetAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problemMethodNotAllowed(w)
+		return
+	}
+	if auditLogPath == "" {
+		problemNotFound(w, "Audit log not configured")
+		return
+	}
+
+	since := time.Time{}
+	if s := r.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			problemBadRequest(w, "Invalid since parameter - expected an RFC3339 timestamp")
+			return
+		}
+		since = t
+	}
+
+	records, err := audit.ReadSince(auditLogPath, since)
+	if err != nil {
+		problemInternal(w, fmt.Sprintf("Failed to read audit log: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for _, rec := range records {
+		enc.Encode(rec)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
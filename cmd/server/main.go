@@ -1,47 +1,80 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/s-gruneberg/jobWorker/internal/audit"
+	"github.com/s-gruneberg/jobWorker/internal/auth"
 	"github.com/s-gruneberg/jobWorker/internal/jobworker"
+	"github.com/s-gruneberg/jobWorker/internal/policy"
+	"github.com/s-gruneberg/jobWorker/internal/server"
 )
 
-type User struct {
-	Token string
-	Role  string
-}
-
-var tokens = map[string]User{
-	"admin-token-123":    {Token: "admin-token-123", Role: "admin"},
-	"operator-token-456": {Token: "operator-token-456", Role: "operator"},
-	"viewer-token-789":   {Token: "viewer-token-789", Role: "viewer"},
-}
-
 var rolePermissions = map[string]map[string]bool{
 	"admin": {
-		"start":  true,
-		"stop":   true,
-		"status": true,
-		"output": true,
+		"start":         true,
+		"stop":          true,
+		"status":        true,
+		"output":        true,
+		"audit":         true,
+		"list":          true,
+		"stats":         true,
+		"policy_reload": true,
 	},
 	"operator": {
 		"start":  true,
 		"status": true,
 		"output": true,
+		"list":   true,
+		"stats":  true,
 	},
 	"viewer": {
 		"status": true,
 		"output": true,
+		"list":   true,
+		"stats":  true,
 	},
 }
 
+// authenticators are tried in order by authMiddleware; the first one to
+// resolve a Principal from the request wins. Populated at startup from the
+// --auth flag.
+var authenticators []auth.Authenticator
+
+type principalKeyType struct{}
+
+var principalKey principalKeyType
+
+// principalFromContext returns the Principal authMiddleware stored in ctx.
+func principalFromContext(ctx context.Context) (auth.Principal, bool) {
+	p, ok := ctx.Value(principalKey).(auth.Principal)
+	return p, ok
+}
+
 type StartJobRequest struct {
-	Command string   `json:"command"`
-	Args    []string `json:"args"`
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Limits  jobworker.Limits  `json:"limits,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	WorkDir string            `json:"workdir,omitempty"`
+}
+
+type PolicyReloadResponse struct {
+	Reloaded bool `json:"reloaded"`
+}
+
+type StatsResponse struct {
+	Stats *jobworker.ResourceStats `json:"stats"`
 }
 
 type StartJobResponse struct {
@@ -63,125 +96,364 @@ type StatusResponse struct {
 
 func handleStartJob(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		problemMethodNotAllowed(w)
 		return
 	}
 
 	var req StartJobRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problemCommandRejected(w, "Invalid request body")
 		return
 	}
 	if req.Command == "" {
-		http.Error(w, "Command is required", http.StatusBadRequest)
+		problemCommandRejected(w, "Command is required")
 		return
 	}
 
-	auth := r.Header.Get("Authorization")
-	if !strings.HasPrefix(auth, "Bearer ") {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+	owner := ""
+	principal, _ := principalFromContext(r.Context())
+	if principal.ID != "" {
+		owner = principal.ID
+		if jobworker.CountRunning(owner) >= maxConcurrentJobsPerPrincipal {
+			recordAudit(r, principal, "start", "", req.Command, req.Args, auditResultDenied, "concurrent job quota exceeded")
+			problemQuotaExceeded(w, "", "concurrent job quota exceeded")
+			return
+		}
 	}
-	token := auth[7:]
 
-	role := ""
-	if t, exists := tokens[token]; exists {
-		role = t.Role
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "queue"
 	}
 
-	if role == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	id, err := jobworker.StartScheduled(owner, principal.Role, req.Command, mode, req.Limits, activePolicy, req.Env, req.WorkDir, req.Args...)
+	if errors.Is(err, jobworker.ErrAtCapacity) {
+		recordAudit(r, principal, "start", "", req.Command, req.Args, auditResultDenied, "at capacity")
+		problemQuotaExceeded(w, "", "at capacity")
+		return
+	}
+	if errors.Is(err, jobworker.ErrPolicyDenied) {
+		recordAudit(r, principal, "start", "", req.Command, req.Args, auditResultDenied, err.Error())
+		problemCommandRejected(w, err.Error())
 		return
 	}
-
-	id, err := jobworker.Start(req.Command, req.Args...)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to start job: %v", err), http.StatusInternalServerError)
+		recordAudit(r, principal, "start", "", req.Command, req.Args, auditResultFailure, err.Error())
+		problemInternal(w, fmt.Sprintf("Failed to start job: %v", err))
 		return
 	}
 
+	recordAudit(r, principal, "start", id, req.Command, req.Args, auditResultSuccess, "")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(StartJobResponse{JobID: id})
 }
 
 func handleGetOutput(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		problemMethodNotAllowed(w)
 		return
 	}
 
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 { // base/jobs/output/id
-		http.Error(w, "Invalid URL - missing job ID", http.StatusBadRequest)
+		problemBadRequest(w, "Invalid URL - missing job ID")
 		return
 	}
 	// base/jobs/output/id
 	id := pathParts[3]
 	if id == "" {
-		http.Error(w, "Invalid URL - empty job ID", http.StatusBadRequest)
+		problemBadRequest(w, "Invalid URL - empty job ID")
 		return
 	}
 
+	principal, _ := principalFromContext(r.Context())
+
 	stdout, stderr, err := jobworker.GetOutput(id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Job not found: %v", err), http.StatusNotFound)
+		recordAudit(r, principal, "output", id, "", nil, auditResultFailure, err.Error())
+		problemJobNotFound(w, id, err.Error())
 		return
 	}
+
+	recordAudit(r, principal, "output", id, "", nil, auditResultSuccess, "")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(OutputResponse{Stdout: stdout, Stderr: stderr})
 }
 
 func handleGetStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		problemMethodNotAllowed(w)
 		return
 	}
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 { // base/jobs/output/id
-		http.Error(w, "Invalid URL - missing job ID", http.StatusBadRequest)
+		problemBadRequest(w, "Invalid URL - missing job ID")
 		return
 	}
 	// base/jobs/output/id
 	id := pathParts[3]
 	if id == "" {
-		http.Error(w, "Invalid URL - empty job ID", http.StatusBadRequest)
+		problemBadRequest(w, "Invalid URL - empty job ID")
 		return
 	}
+	principal, _ := principalFromContext(r.Context())
+
 	status, err := jobworker.GetStatus(id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Job not found: %v", err), http.StatusNotFound)
+		recordAudit(r, principal, "status", id, "", nil, auditResultFailure, err.Error())
+		problemJobNotFound(w, id, err.Error())
 		return
 	}
+
+	recordAudit(r, principal, "status", id, "", nil, auditResultSuccess, "")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(StatusResponse{Status: status})
-
 }
 
+// defaultStopGraceTimeout is how long handleStopJob waits after SIGTERM
+// before jobworker.Stop escalates to SIGKILL, unless overridden by
+// ?grace_seconds= on the request.
+const defaultStopGraceTimeout = 5 * time.Second
+
 func handleStopJob(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		problemMethodNotAllowed(w)
 		return
 	}
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 { // base/jobs/output/id
-		http.Error(w, "Invalid URL - missing job ID", http.StatusBadRequest)
+		problemBadRequest(w, "Invalid URL - missing job ID")
 		return
 	}
 	// base/jobs/output/id
 	id := pathParts[3]
 	if id == "" {
-		http.Error(w, "Invalid URL - empty job ID", http.StatusBadRequest)
+		problemBadRequest(w, "Invalid URL - empty job ID")
 		return
 	}
-	err := jobworker.Stop(id)
+	principal, _ := principalFromContext(r.Context())
+
+	graceTimeout := defaultStopGraceTimeout
+	if s := r.URL.Query().Get("grace_seconds"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			graceTimeout = time.Duration(n) * time.Second
+		}
+	}
+
+	err := jobworker.Stop(id, graceTimeout)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Job not found: %v", err), http.StatusNotFound)
+		recordAudit(r, principal, "stop", id, "", nil, auditResultFailure, err.Error())
+		problemJobNotFound(w, id, err.Error())
 		return
 	}
+
+	recordAudit(r, principal, "stop", id, "", nil, auditResultSuccess, "")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(StopJobResponse{JobID: id})
 }
 
+// streamFrame is one newline-delimited JSON frame emitted by handleStreamJob.
+// Either Stream/Data/Offset (a log chunk) or Status (the terminal frame) is
+// populated.
+type streamFrame struct {
+	Stream string `json:"stream,omitempty"`
+	Data   string `json:"data,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+func handleStreamJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problemMethodNotAllowed(w)
+		return
+	}
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 { // base/jobs/stream/id
+		problemBadRequest(w, "Invalid URL - missing job ID")
+		return
+	}
+	id := pathParts[3]
+	if id == "" {
+		problemBadRequest(w, "Invalid URL - empty job ID")
+		return
+	}
+
+	fromOffset := parseStreamOffset(r)
+	principal, _ := principalFromContext(r.Context())
+
+	chunks, cancel, err := jobworker.Subscribe(id, fromOffset)
+	if err != nil {
+		recordAudit(r, principal, "output", id, "", nil, auditResultFailure, err.Error())
+		problemJobNotFound(w, id, err.Error())
+		return
+	}
+	defer cancel()
+	recordAudit(r, principal, "output", id, "", nil, auditResultSuccess, "")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problemInternal(w, "Streaming unsupported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				status, _ := jobworker.GetStatus(id)
+				enc.Encode(streamFrame{Status: status})
+				flusher.Flush()
+				return
+			}
+			enc.Encode(streamFrame{Stream: chunk.Stream, Data: string(chunk.Data), Offset: chunk.Offset})
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// parseStreamOffset reads the resume offset from a Range: bytes=N- header or
+// a ?from=N query parameter, defaulting to 0 (stream from the beginning).
+func parseStreamOffset(r *http.Request) int64 {
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		spec := strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-")
+		if n, err := strconv.ParseInt(spec, 10, 64); err == nil {
+			return n
+		}
+	}
+	if from := r.URL.Query().Get("from"); from != "" {
+		if n, err := strconv.ParseInt(from, 10, 64); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// handleTailJob streams a job's combined stdout/stderr from the start as
+// Server-Sent Events, so a viewer can `curl` it directly and watch a
+// long-running command's output live. Unlike handleStreamJob it always
+// starts at offset 0 and isn't resumable - it's the human-friendly tail, not
+// the programmatic one.
+func handleTailJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problemMethodNotAllowed(w)
+		return
+	}
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 { // base/jobs/logs/id
+		problemBadRequest(w, "Invalid URL - missing job ID")
+		return
+	}
+	id := pathParts[3]
+	if id == "" {
+		problemBadRequest(w, "Invalid URL - empty job ID")
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+
+	chunks, err := jobworker.Tail(id, r.Context())
+	if err != nil {
+		recordAudit(r, principal, "output", id, "", nil, auditResultFailure, err.Error())
+		problemJobNotFound(w, id, err.Error())
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problemInternal(w, "Streaming unsupported")
+		return
+	}
+
+	recordAudit(r, principal, "output", id, "", nil, auditResultSuccess, "")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for chunk := range chunks {
+		fmt.Fprintf(w, "event: %s\n", chunk.Stream)
+		for _, line := range strings.Split(strings.TrimRight(string(chunk.Data), "\n"), "\n") {
+			fmt.Fprintf(w, "data: %s\n", line)
+		}
+		fmt.Fprint(w, "\n")
+		flusher.Flush()
+	}
+
+	status, _ := jobworker.GetStatus(id)
+	fmt.Fprintf(w, "event: status\ndata: %s\n\n", status)
+	flusher.Flush()
+}
+
+// handleGetStats returns the resource accounting collected for a job's
+// cgroup (cpu.stat/memory.peak/io.stat) once it has exited.
+func handleGetStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problemMethodNotAllowed(w)
+		return
+	}
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 { // base/jobs/stats/id
+		problemBadRequest(w, "Invalid URL - missing job ID")
+		return
+	}
+	id := pathParts[3]
+	if id == "" {
+		problemBadRequest(w, "Invalid URL - empty job ID")
+		return
+	}
+	principal, _ := principalFromContext(r.Context())
+
+	stats, err := jobworker.GetStats(id)
+	if err != nil {
+		recordAudit(r, principal, "stats", id, "", nil, auditResultFailure, err.Error())
+		problemJobNotFound(w, id, err.Error())
+		return
+	}
+
+	recordAudit(r, principal, "stats", id, "", nil, auditResultSuccess, "")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StatsResponse{Stats: stats})
+}
+
+// handleListJobs returns every tracked job, annotated with queue position,
+// for GET /jobs/.
+func handleListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		problemMethodNotAllowed(w)
+		return
+	}
+	if r.URL.Path != "/jobs/" {
+		problemNotFound(w, "")
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	jobs := jobworker.List()
+	recordAudit(r, principal, "list", "", "", nil, auditResultSuccess, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// backpressureMiddleware advertises the MaxConcurrent cap and current
+// running-job count on every /jobs/* response so clients can implement
+// backpressure without first attempting a start.
+func backpressureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-JobWorker-Max-Jobs", strconv.Itoa(jobworker.MaxConcurrent))
+		w.Header().Set("X-JobWorker-Active-Jobs", strconv.Itoa(jobworker.ActiveCount()))
+		next.ServeHTTP(w, r)
+	})
+}
+
 func isAuthorized(role, action string) bool {
 	actions, ok := rolePermissions[role]
 	if !ok {
@@ -190,66 +462,143 @@ func isAuthorized(role, action string) bool {
 	return actions[action]
 }
 
+// authMiddleware resolves the caller's Principal via the first configured
+// Authenticator that accepts the request, then enforces rolePermissions for
+// action.
 func authMiddleware(action string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			auth := r.Header.Get("Authorization")
-			if auth == "" {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-
-			if !strings.HasPrefix(auth, "Bearer ") {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			principal, ok := authenticate(r)
+			if !ok {
+				recordAudit(r, auth.Principal{}, action, "", "", nil, auditResultDenied, "authentication failed")
+				problemAuth(w, http.StatusUnauthorized, "Unauthorized", "authentication failed")
 				return
 			}
 
-			token := auth[7:]
-			if token == "" {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-
-			if _, exists := tokens[token]; !exists {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			role := ""
-			if t, exists := tokens[token]; exists {
-				role = t.Role
-			}
-
-			if role == "" {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			if !isAuthorized(role, action) {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+			if !isAuthorized(principal.Role, action) {
+				recordAudit(r, principal, action, "", "", nil, auditResultDenied, "not authorized for action")
+				problemAuth(w, http.StatusForbidden, "Forbidden", "not authorized for action")
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), principalKey, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// authenticate tries each configured Authenticator in order, returning the
+// first Principal any of them resolves.
+func authenticate(r *http.Request) (auth.Principal, bool) {
+	for _, a := range authenticators {
+		if p, err := a.Authenticate(r); err == nil {
+			return p, true
+		}
+	}
+	return auth.Principal{}, false
+}
+
+// defaultStaticTokens preserves the original hardcoded bearer tokens for the
+// "static" authenticator, used for local testing.
+var defaultStaticTokens = map[string]auth.Principal{
+	"admin-token-123":    {ID: "admin-token-123", Role: "admin"},
+	"operator-token-456": {ID: "operator-token-456", Role: "operator"},
+	"viewer-token-789":   {ID: "viewer-token-789", Role: "viewer"},
+}
+
 func main() {
+	mappingPath := flag.String("role-mapping", "role_mapping.json", "path to certificate-to-role mapping config (mtls auth)")
+	caFile := flag.String("ca-file", "ca.pem", "path to CA certificate used to verify client certs (mtls auth)")
+	certFile := flag.String("cert-file", "cert.pem", "path to the server's TLS certificate")
+	keyFile := flag.String("key-file", "key.pem", "path to the server's TLS private key")
+	authModes := flag.String("auth", "mtls", "comma-separated list of enabled authenticators: mtls,static,oidc")
+	oidcIssuer := flag.String("oidc-issuer", "", "OIDC issuer URL (oidc auth)")
+	oidcAudience := flag.String("oidc-audience", "", "expected JWT audience (oidc auth)")
+	auditLogFile := flag.String("audit-log", "audit.log", "path to the tamper-evident audit log (JSONL)")
+	maxConcurrent := flag.Int("max-concurrent", 0, "maximum number of jobs running at once (0 = unlimited)")
+	policyFile := flag.String("policy-file", "", "path to the per-role command policy config (JSON); unset means every job is denied")
+	flag.Parse()
+
+	jobworker.MaxConcurrent = *maxConcurrent
+
+	if *policyFile != "" {
+		rs, err := policy.Load(*policyFile)
+		if err != nil {
+			panic(fmt.Sprintf("loading policy config: %v", err))
+		}
+		activeRuleSet = rs
+		activePolicy = rs
+		policyConfigPath = *policyFile
+	}
+
+	logger, err := audit.Open(*auditLogFile)
+	if err != nil {
+		panic(fmt.Sprintf("opening audit log: %v", err))
+	}
+	defer logger.Close()
+	auditLog = logger
+	auditLogPath = *auditLogFile
+
+	var mtlsEnabled, staticEnabled bool
+
+	for _, mode := range strings.Split(*authModes, ",") {
+		switch strings.TrimSpace(mode) {
+		case "mtls":
+			m, err := auth.LoadRoleMapping(*mappingPath)
+			if err != nil {
+				panic(fmt.Sprintf("loading role mapping: %v", err))
+			}
+			authenticators = append(authenticators, &auth.MTLSAuth{Mapping: m})
+			mtlsEnabled = true
+		case "static":
+			authenticators = append(authenticators, &auth.StaticTokenAuth{Tokens: defaultStaticTokens})
+			staticEnabled = true
+		case "oidc":
+			authenticators = append(authenticators, &auth.OIDCAuth{Issuer: *oidcIssuer, Audience: *oidcAudience})
+		}
+	}
+
 	mux := http.NewServeMux()
 
-	mux.Handle("/jobs/start", authMiddleware("start")(http.HandlerFunc(handleStartJob)))
-	mux.Handle("/jobs/stop/", authMiddleware("stop")(http.HandlerFunc(handleStopJob)))
-	mux.Handle("/jobs/status/", authMiddleware("status")(http.HandlerFunc(handleGetStatus)))
-	mux.Handle("/jobs/output/", authMiddleware("output")(http.HandlerFunc(handleGetOutput)))
+	mux.Handle("/jobs/start", backpressureMiddleware(authMiddleware("start")(rateLimitMiddleware("start")(http.HandlerFunc(handleStartJob)))))
+	mux.Handle("/jobs/stop/", backpressureMiddleware(authMiddleware("stop")(rateLimitMiddleware("stop")(http.HandlerFunc(handleStopJob)))))
+	mux.Handle("/jobs/status/", backpressureMiddleware(authMiddleware("status")(rateLimitMiddleware("status")(http.HandlerFunc(handleGetStatus)))))
+	mux.Handle("/jobs/output/", backpressureMiddleware(authMiddleware("output")(rateLimitMiddleware("output")(http.HandlerFunc(handleGetOutput)))))
+	mux.Handle("/jobs/stream/", backpressureMiddleware(authMiddleware("output")(rateLimitMiddleware("output")(http.HandlerFunc(handleStreamJob)))))
+	mux.Handle("/jobs/logs/", backpressureMiddleware(authMiddleware("output")(rateLimitMiddleware("output")(http.HandlerFunc(handleTailJob)))))
+	mux.Handle("/jobs/stats/", backpressureMiddleware(authMiddleware("stats")(rateLimitMiddleware("stats")(http.HandlerFunc(handleGetStats)))))
+	mux.Handle("/jobs/", backpressureMiddleware(authMiddleware("list")(rateLimitMiddleware("list")(http.HandlerFunc(handleListJobs)))))
+	mux.Handle("/audit", authMiddleware("audit")(rateLimitMiddleware("audit")(http.HandlerFunc(handleGetAudit))))
+	mux.Handle("/policy/reload", authMiddleware("policy_reload")(rateLimitMiddleware("policy_reload")(http.HandlerFunc(handlePolicyReload))))
+
+	if _, err := os.Stat(*certFile); err != nil {
+		panic(fmt.Sprintf("%s not found - HTTPS is required", *certFile))
+	}
+	if _, err := os.Stat(*keyFile); err != nil {
+		panic(fmt.Sprintf("%s not found - HTTPS is required", *keyFile))
+	}
 
-	if _, err := os.Stat("cert.pem"); err != nil {
-		panic("cert.pem not found - HTTPS is required")
+	srvConfig := server.Config{CertFile: *certFile, KeyFile: *keyFile, TLSMinVersion: tls.VersionTLS13}
+	if mtlsEnabled {
+		srvConfig.CAFile = *caFile
+		// With static also enabled, don't hard-fail the TLS handshake for a
+		// client that skips its certificate - let it through and fall back
+		// to the bearer-token authenticator in authenticate().
+		srvConfig.AllowMissingClientCert = staticEnabled
+	}
+	tlsConfig, err := srvConfig.TLSConfig()
+	if err != nil {
+		panic(fmt.Sprintf("building TLS config: %v", err))
 	}
-	if _, err := os.Stat("key.pem"); err != nil {
-		panic("key.pem not found - HTTPS is required")
+
+	httpServer := &http.Server{
+		Addr:      ":8080",
+		Handler:   mux,
+		TLSConfig: tlsConfig,
 	}
 
 	fmt.Println("Server starting on https://localhost:8080")
-	if err := http.ListenAndServeTLS(":8080", "cert.pem", "key.pem", mux); err != nil {
+	if err := httpServer.ListenAndServeTLS(*certFile, *keyFile); err != nil {
 		panic(err)
 	}
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/s-gruneberg/jobWorker/internal/policy"
+)
+
+// activePolicy is the Policy every handleStartJob invocation is checked
+// against. It defaults to DenyAll: the admin role can invoke arbitrary
+// binaries, so a server started without --policy-file must fail closed
+// rather than run every command unrestricted. main() replaces it with a
+// loaded *policy.RuleSet when the flag is set, at which point any role
+// without an explicit Rule in that config is denied too (RuleSet's own
+// fail-closed behavior).
+var activePolicy policy.Policy = policy.DenyAll{}
+
+// activeRuleSet is the concrete RuleSet backing activePolicy, kept so
+// handlePolicyReload can re-read its config file. It is nil while
+// activePolicy is still the DenyAll default.
+var activeRuleSet *policy.RuleSet
+
+// policyConfigPath is the file handlePolicyReload re-reads on each request.
+var policyConfigPath string
+
+// handlePolicyReload re-reads the policy config file from disk and
+// atomically swaps in its rules, without restarting the server. Restricted
+// to admins by rolePermissions.
+func handlePolicyReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		problemMethodNotAllowed(w)
+		return
+	}
+	if activeRuleSet == nil {
+		problemBadRequest(w, "No policy config file configured - start the server with --policy-file")
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	if err := activeRuleSet.Reload(policyConfigPath); err != nil {
+		recordAudit(r, principal, "policy_reload", "", "", nil, auditResultFailure, err.Error())
+		problemInternal(w, err.Error())
+		return
+	}
+
+	recordAudit(r, principal, "policy_reload", "", "", nil, auditResultSuccess, "")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PolicyReloadResponse{Reloaded: true})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem type URIs this API commits to (RFC 7807 SS3.1). "about:blank" means
+// the status code alone describes the failure; the rest name specific
+// job-worker failure modes so a scripted client can switch on "type" instead
+// of parsing prose out of "detail".
+const (
+	problemTypeBlank           = "about:blank"
+	problemTypeJobNotFound     = "/problems/job-not-found"
+	problemTypeUnauthorized    = "/problems/unauthorized"
+	problemTypeCommandRejected = "/problems/command-rejected"
+	problemTypeQuotaExceeded   = "/problems/quota-exceeded"
+)
+
+// Problem is an RFC 7807 application/problem+json error body.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// writeProblem writes p as application/problem+json, using p.Status as the
+// response's HTTP status code.
+func writeProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// jobInstance returns the canonical instance URI (RFC 7807 SS3.3) for a
+// job-scoped problem, or "" if id is unknown at this point in the request.
+func jobInstance(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "/jobs/" + id
+}
+
+// problemMethodNotAllowed reports a request using an unsupported HTTP method.
+func problemMethodNotAllowed(w http.ResponseWriter) {
+	writeProblem(w, Problem{Type: problemTypeBlank, Title: "Method not allowed", Status: http.StatusMethodNotAllowed})
+}
+
+// problemBadRequest reports a malformed request, such as a missing or
+// unparsable path segment or query parameter.
+func problemBadRequest(w http.ResponseWriter, detail string) {
+	writeProblem(w, Problem{Type: problemTypeBlank, Title: "Bad request", Status: http.StatusBadRequest, Detail: detail})
+}
+
+// problemNotFound reports a 404 that isn't scoped to a specific job, such as
+// an unrecognized route.
+func problemNotFound(w http.ResponseWriter, detail string) {
+	writeProblem(w, Problem{Type: problemTypeBlank, Title: "Not found", Status: http.StatusNotFound, Detail: detail})
+}
+
+// problemJobNotFound reports a request against a job ID jobworker doesn't
+// know about.
+func problemJobNotFound(w http.ResponseWriter, id, detail string) {
+	writeProblem(w, Problem{Type: problemTypeJobNotFound, Title: "Job not found", Status: http.StatusNotFound, Detail: detail, Instance: jobInstance(id)})
+}
+
+// problemAuth reports an authentication failure (401) or an authorization
+// denial (403).
+func problemAuth(w http.ResponseWriter, status int, title, detail string) {
+	writeProblem(w, Problem{Type: problemTypeUnauthorized, Title: title, Status: status, Detail: detail})
+}
+
+// problemCommandRejected reports a job submission that was syntactically
+// invalid, such as a missing command, or that the active policy denied.
+func problemCommandRejected(w http.ResponseWriter, detail string) {
+	writeProblem(w, Problem{Type: problemTypeCommandRejected, Title: "Command rejected", Status: http.StatusBadRequest, Detail: detail})
+}
+
+// problemQuotaExceeded reports a request rejected for exceeding a
+// per-principal concurrency quota, the scheduler's MaxConcurrent capacity,
+// or the per-principal request rate limit.
+func problemQuotaExceeded(w http.ResponseWriter, id, detail string) {
+	w.Header().Set("Retry-After", "1")
+	writeProblem(w, Problem{Type: problemTypeQuotaExceeded, Title: "Quota exceeded", Status: http.StatusTooManyRequests, Detail: detail, Instance: jobInstance(id)})
+}
+
+// problemInternal reports an unexpected server-side failure.
+func problemInternal(w http.ResponseWriter, detail string) {
+	writeProblem(w, Problem{Type: problemTypeBlank, Title: "Internal server error", Status: http.StatusInternalServerError, Detail: detail})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/s-gruneberg/jobWorker/internal/auth"
+)
+
+// rolesRatePerSecond caps sustained request throughput per role.
+var rolesRatePerSecond = map[string]float64{
+	"admin":    100,
+	"operator": 20,
+	"viewer":   5,
+}
+
+// maxConcurrentJobsPerPrincipal caps how many jobs a single principal may
+// have running at once, enforced in handleStartJob.
+const maxConcurrentJobsPerPrincipal = 5
+
+// tokenBucket is a simple token-bucket rate limiter, refilled at rate tokens
+// per second up to a burst equal to rate.
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	rate     float64
+	lastFill time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{tokens: rate, rate: rate, lastFill: time.Now()}
+}
+
+// allow reports whether a request may proceed now, consuming one token if so.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.lastFill).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// remaining returns the number of tokens currently available, for the
+// X-RateLimit-Remaining header.
+func (b *tokenBucket) remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.tokens)
+}
+
+type rateLimitKey struct {
+	principalID string
+	action      string
+}
+
+// rateLimitersCap bounds how many distinct (principalID, action) limiters
+// rateLimiters holds onto at once. principalID comes from untrusted token
+// claims (in particular an OIDC "sub"), so without a bound a client minting
+// new identities could grow this map forever; once full, rateLimiterFor
+// evicts the least recently used limiter to make room for a new one.
+const rateLimitersCap = 10000
+
+// rateLimiterEntry is the value stored in rateLimiterLRU's list, so an
+// eviction can delete the matching rateLimiters entry by key.
+type rateLimiterEntry struct {
+	key     rateLimitKey
+	limiter *tokenBucket
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = make(map[rateLimitKey]*list.Element)
+	rateLimiterLRU = list.New()
+)
+
+// rateLimiterFor returns the token bucket for (principal, action), creating
+// one seeded from rolesRatePerSecond if this is the first request seen for
+// that pair, and marking it as most recently used either way.
+func rateLimiterFor(principal auth.Principal, action string) *tokenBucket {
+	key := rateLimitKey{principalID: principal.ID, action: action}
+
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	if elem, ok := rateLimiters[key]; ok {
+		rateLimiterLRU.MoveToFront(elem)
+		return elem.Value.(*rateLimiterEntry).limiter
+	}
+
+	rate, ok := rolesRatePerSecond[principal.Role]
+	if !ok {
+		rate = rolesRatePerSecond["viewer"]
+	}
+	b := newTokenBucket(rate)
+	elem := rateLimiterLRU.PushFront(&rateLimiterEntry{key: key, limiter: b})
+	rateLimiters[key] = elem
+
+	if rateLimiterLRU.Len() > rateLimitersCap {
+		oldest := rateLimiterLRU.Back()
+		rateLimiterLRU.Remove(oldest)
+		delete(rateLimiters, oldest.Value.(*rateLimiterEntry).key)
+	}
+
+	return b
+}
+
+// rateLimitMiddleware enforces a per-principal, per-action token-bucket rate
+// limit. It must run after authMiddleware so the Principal is already in the
+// request context.
+func rateLimitMiddleware(action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := principalFromContext(r.Context())
+			if !ok {
+				problemAuth(w, http.StatusUnauthorized, "Unauthorized", "missing principal")
+				return
+			}
+
+			limiter := rateLimiterFor(principal, action)
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(limiter.remaining()))
+
+			if !limiter.allow() {
+				problemQuotaExceeded(w, "", "rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
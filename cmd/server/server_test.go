@@ -1,89 +1,75 @@
 package main
 
 import (
+	"container/list"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/s-gruneberg/jobWorker/internal/audit"
+	"github.com/s-gruneberg/jobWorker/internal/auth"
 	"github.com/s-gruneberg/jobWorker/internal/jobworker"
+	"github.com/s-gruneberg/jobWorker/internal/policy"
 )
 
+// TestMain runs the package's tests with activePolicy defaulting to
+// AllowAll, since most of them predate the policy subsystem and exercise
+// unrelated behavior. The production default in policy.go is DenyAll; tests
+// that specifically exercise policy enforcement narrow it with withPolicy.
+func TestMain(m *testing.M) {
+	activePolicy = policy.AllowAll{}
+	os.Exit(m.Run())
+}
+
 func TestHandleStartJob(t *testing.T) {
 	tests := []struct {
 		name           string
 		method         string
 		body           string
-		authHeader     string
 		expectedStatus int
-		expectedBody   string
+		expectedDetail string
 	}{
 		{
 			name:           "successful job start",
 			method:         "POST",
 			body:           `{"command": "echo", "args": ["hello"]}`,
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"job_id":"1"}`,
 		},
 		{
 			name:           "wrong method",
 			method:         "GET",
 			body:           `{"command": "echo", "args": ["hello"]}`,
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method not allowed\n",
 		},
 		{
 			name:           "invalid JSON",
 			method:         "POST",
 			body:           `{"command": "echo", "args": ["hello"`,
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid request body\n",
+			expectedDetail: "Invalid request body",
 		},
 		{
 			name:           "missing command",
 			method:         "POST",
 			body:           `{"args": ["hello"]}`,
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Command is required\n",
+			expectedDetail: "Command is required",
 		},
 		{
 			name:           "empty command",
 			method:         "POST",
 			body:           `{"command": "", "args": ["hello"]}`,
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Command is required\n",
-		},
-		{
-			name:           "missing auth header",
-			method:         "POST",
-			body:           `{"command": "echo", "args": ["hello"]}`,
-			authHeader:     "",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "Unauthorized\n",
-		},
-		{
-			name:           "invalid auth format",
-			method:         "POST",
-			body:           `{"command": "echo", "args": ["hello"]}`,
-			authHeader:     "admin-token-123",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "Unauthorized\n",
-		},
-		{
-			name:           "invalid token",
-			method:         "POST",
-			body:           `{"command": "echo", "args": ["hello"]}`,
-			authHeader:     "Bearer invalid-token",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "Unauthorized\n",
+			expectedDetail: "Command is required",
 		},
 	}
 
@@ -96,10 +82,6 @@ func TestHandleStartJob(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if tt.authHeader != "" {
-				req.Header.Set("Authorization", tt.authHeader)
-			}
-
 			rr := httptest.NewRecorder()
 			handler := http.HandlerFunc(handleStartJob)
 			handler.ServeHTTP(rr, req)
@@ -117,9 +99,7 @@ func TestHandleStartJob(t *testing.T) {
 					t.Errorf("expected job_id in response, got empty string")
 				}
 			} else {
-				if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tt.expectedBody) {
-					t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
-				}
+				assertProblemDetail(t, rr, tt.expectedDetail)
 			}
 		})
 	}
@@ -129,40 +109,34 @@ func TestHandleGetOutput(t *testing.T) {
 		name           string
 		method         string
 		jobID          string
-		authHeader     string
 		expectedStatus int
-		expectedBody   string
+		expectedDetail string
 	}{
 		{
 			name:           "successful output retrieval",
 			method:         "GET",
 			jobID:          "1",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "wrong method",
 			method:         "POST",
 			jobID:          "1",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method not allowed\n",
 		},
 		{
 			name:           "empty job ID",
 			method:         "GET",
 			jobID:          "",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid URL - empty job ID\n",
+			expectedDetail: "Invalid URL - empty job ID",
 		},
 		{
 			name:           "job not found",
 			method:         "GET",
 			jobID:          "999",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "Job not found: job not found\n",
+			expectedDetail: "job not found",
 		},
 	}
 
@@ -184,10 +158,6 @@ func TestHandleGetOutput(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if tt.authHeader != "" {
-				req.Header.Set("Authorization", tt.authHeader)
-			}
-
 			rr := httptest.NewRecorder()
 			handler := http.HandlerFunc(handleGetOutput)
 			handler.ServeHTTP(rr, req)
@@ -196,10 +166,8 @@ func TestHandleGetOutput(t *testing.T) {
 				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
 			}
 
-			if tt.expectedBody != "" {
-				if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tt.expectedBody) {
-					t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
-				}
+			if tt.expectedStatus != http.StatusOK {
+				assertProblemDetail(t, rr, tt.expectedDetail)
 			}
 
 			if tt.expectedStatus == http.StatusOK {
@@ -220,40 +188,34 @@ func TestHandleGetStatus(t *testing.T) {
 		name           string
 		method         string
 		jobID          string
-		authHeader     string
 		expectedStatus int
-		expectedBody   string
+		expectedDetail string
 	}{
 		{
 			name:           "successful status retrieval",
 			method:         "GET",
 			jobID:          "1",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "wrong method",
 			method:         "POST",
 			jobID:          "1",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method not allowed\n",
 		},
 		{
 			name:           "empty job ID",
 			method:         "GET",
 			jobID:          "",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid URL - empty job ID\n",
+			expectedDetail: "Invalid URL - empty job ID",
 		},
 		{
 			name:           "job not found",
 			method:         "GET",
 			jobID:          "999",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "Job not found: job not found\n",
+			expectedDetail: "job not found",
 		},
 	}
 
@@ -274,10 +236,6 @@ func TestHandleGetStatus(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if tt.authHeader != "" {
-				req.Header.Set("Authorization", tt.authHeader)
-			}
-
 			rr := httptest.NewRecorder()
 			handler := http.HandlerFunc(handleGetStatus)
 			handler.ServeHTTP(rr, req)
@@ -286,10 +244,8 @@ func TestHandleGetStatus(t *testing.T) {
 				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
 			}
 
-			if tt.expectedBody != "" {
-				if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tt.expectedBody) {
-					t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
-				}
+			if tt.expectedStatus != http.StatusOK {
+				assertProblemDetail(t, rr, tt.expectedDetail)
 			}
 
 			if tt.expectedStatus == http.StatusOK {
@@ -310,40 +266,34 @@ func TestHandleStopJob(t *testing.T) {
 		name           string
 		method         string
 		jobID          string
-		authHeader     string
 		expectedStatus int
-		expectedBody   string
+		expectedDetail string
 	}{
 		{
 			name:           "successful job stop",
 			method:         "PUT",
 			jobID:          "1",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "wrong method",
 			method:         "GET",
 			jobID:          "1",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method not allowed\n",
 		},
 		{
 			name:           "empty job ID",
 			method:         "PUT",
 			jobID:          "",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid URL - empty job ID\n",
+			expectedDetail: "Invalid URL - empty job ID",
 		},
 		{
 			name:           "job not found",
 			method:         "PUT",
 			jobID:          "999",
-			authHeader:     "Bearer admin-token-123",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "Job not found: job not found\n",
+			expectedDetail: "job not found",
 		},
 	}
 
@@ -364,10 +314,6 @@ func TestHandleStopJob(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if tt.authHeader != "" {
-				req.Header.Set("Authorization", tt.authHeader)
-			}
-
 			rr := httptest.NewRecorder()
 			handler := http.HandlerFunc(handleStopJob)
 			handler.ServeHTTP(rr, req)
@@ -376,10 +322,8 @@ func TestHandleStopJob(t *testing.T) {
 				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.expectedStatus)
 			}
 
-			if tt.expectedBody != "" {
-				if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tt.expectedBody) {
-					t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
-				}
+			if tt.expectedStatus != http.StatusOK {
+				assertProblemDetail(t, rr, tt.expectedDetail)
 			}
 
 			if tt.expectedStatus == http.StatusOK {
@@ -428,94 +372,637 @@ func TestIsAuthorized(t *testing.T) {
 	}
 }
 
+// TestAuthMiddlewareNoCertificate covers the case where the TLS layer somehow
+// hands the middleware a request with no verified peer certificate.
+func TestAuthMiddlewareNoCertificate(t *testing.T) {
+	authenticators = []auth.Authenticator{&auth.MTLSAuth{Mapping: &auth.RoleMapping{ByCN: map[string]string{"admin-client": "admin"}}}}
+
+	handler := authMiddleware("start")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestAuthMiddleware presents client certificates issued by a throwaway test
+// CA over a real TLS handshake and asserts role resolution and authorization.
 func TestAuthMiddleware(t *testing.T) {
+	ca, err := auth.NewTestCA()
+	if err != nil {
+		t.Fatalf("failed to create test CA: %v", err)
+	}
+
+	authenticators = []auth.Authenticator{&auth.MTLSAuth{Mapping: &auth.RoleMapping{ByCN: map[string]string{
+		"admin-client":    "admin",
+		"operator-client": "operator",
+		"viewer-client":   "viewer",
+	}}}}
+
+	adminCert, err := ca.IssueClientCert("admin-client")
+	if err != nil {
+		t.Fatalf("failed to issue admin cert: %v", err)
+	}
+	operatorCert, err := ca.IssueClientCert("operator-client")
+	if err != nil {
+		t.Fatalf("failed to issue operator cert: %v", err)
+	}
+	viewerCert, err := ca.IssueClientCert("viewer-client")
+	if err != nil {
+		t.Fatalf("failed to issue viewer cert: %v", err)
+	}
+	unknownCert, err := ca.IssueClientCert("unknown-client")
+	if err != nil {
+		t.Fatalf("failed to issue unknown cert: %v", err)
+	}
+
+	handler := authMiddleware("start")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("success"))
+	}))
+
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
 	tests := []struct {
 		name           string
-		action         string
-		authHeader     string
+		cert           tls.Certificate
 		expectedStatus int
-		expectedBody   string
 	}{
-		{
-			name:           "valid admin token for start",
-			action:         "start",
-			authHeader:     "Bearer admin-token-123",
-			expectedStatus: http.StatusOK,
-		},
-		{
-			name:           "valid operator token for start",
-			action:         "start",
-			authHeader:     "Bearer operator-token-456",
-			expectedStatus: http.StatusOK,
-		},
-		{
-			name:           "viewer cannot start",
-			action:         "start",
-			authHeader:     "Bearer viewer-token-789",
-			expectedStatus: http.StatusForbidden,
-			expectedBody:   "Forbidden\n",
-		},
-		{
-			name:           "missing auth header",
-			action:         "start",
-			authHeader:     "",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "Unauthorized\n",
-		},
-		{
-			name:           "invalid auth format",
-			action:         "start",
-			authHeader:     "admin-token-123",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "Unauthorized\n",
-		},
-		{
-			name:           "empty token",
-			action:         "start",
-			authHeader:     "Bearer ",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "Unauthorized\n",
-		},
-		{
-			name:           "invalid token",
-			action:         "start",
-			authHeader:     "Bearer invalid-token",
-			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   "Unauthorized\n",
-		},
+		{"admin can start", adminCert, http.StatusOK},
+		{"operator can start", operatorCert, http.StatusOK},
+		{"viewer cannot start", viewerCert, http.StatusForbidden},
+		{"unmapped certificate", unknownCert, http.StatusUnauthorized},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("success"))
-			})
-
-			middleware := authMiddleware(tt.action)
-			wrappedHandler := middleware(handler)
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						Certificates:       []tls.Certificate{tt.cert},
+						InsecureSkipVerify: true,
+					},
+				},
+			}
 
-			req, err := http.NewRequest("GET", "/test", nil)
+			resp, err := client.Get(server.URL)
 			if err != nil {
-				t.Fatal(err)
+				t.Fatalf("request failed: %v", err)
 			}
+			defer resp.Body.Close()
 
-			if tt.authHeader != "" {
-				req.Header.Set("Authorization", tt.authHeader)
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.expectedStatus)
 			}
+		})
+	}
+}
 
-			rr := httptest.NewRecorder()
-			wrappedHandler.ServeHTTP(rr, req)
+// withPrincipal attaches a Principal to req's context the way authMiddleware
+// would, so rateLimitMiddleware and handleStartJob's quota check can be
+// exercised without a real TLS handshake.
+func withPrincipal(req *http.Request, principal auth.Principal) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), principalKey, principal))
+}
 
-			if status := rr.Code; status != tt.expectedStatus {
-				t.Errorf("middleware returned wrong status code: got %v want %v", status, tt.expectedStatus)
-			}
+// TestRateLimitMiddlewareTransitionsTo429 hammers a rate-limited handler as an
+// operator and asserts the burst is allowed before the limiter starts
+// rejecting with 429.
+func TestRateLimitMiddlewareTransitionsTo429(t *testing.T) {
+	principal := auth.Principal{ID: "rate-limit-operator", Role: "operator"}
+	burst := int(rolesRatePerSecond["operator"])
+
+	handler := rateLimitMiddleware("start")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < burst; i++ {
+		req := withPrincipal(httptest.NewRequest("POST", "/jobs/start", nil), principal)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want %d", i, rr.Code, http.StatusOK)
+		}
+	}
 
-			if tt.expectedBody != "" {
-				if strings.TrimSpace(rr.Body.String()) != strings.TrimSpace(tt.expectedBody) {
-					t.Errorf("middleware returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
-				}
-			}
-		})
+	req := withPrincipal(httptest.NewRequest("POST", "/jobs/start", nil), principal)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Errorf("expected Retry-After header on 429 response")
+	}
+}
+
+// TestRateLimiterForEvictsLeastRecentlyUsed covers rateLimiters staying
+// bounded at rateLimitersCap by evicting the least recently used limiter,
+// so a high-cardinality principal ID source (e.g. OIDC "sub" claims) can't
+// grow it without bound.
+func TestRateLimiterForEvictsLeastRecentlyUsed(t *testing.T) {
+	rateLimitersMu.Lock()
+	rateLimiters = make(map[rateLimitKey]*list.Element)
+	rateLimiterLRU = list.New()
+	rateLimitersMu.Unlock()
+
+	oldest := auth.Principal{ID: "evict-test-oldest", Role: "viewer"}
+	rateLimiterFor(oldest, "start")
+
+	for i := 0; i < rateLimitersCap; i++ {
+		p := auth.Principal{ID: fmt.Sprintf("evict-test-%d", i), Role: "viewer"}
+		rateLimiterFor(p, "start")
+	}
+
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	if len(rateLimiters) != rateLimitersCap {
+		t.Errorf("got %d limiters, want %d", len(rateLimiters), rateLimitersCap)
+	}
+	if _, ok := rateLimiters[rateLimitKey{principalID: oldest.ID, action: "start"}]; ok {
+		t.Error("expected the least recently used limiter to have been evicted")
+	}
+}
+
+// TestHandleStartJobQuotaExceeded covers a principal who already has
+// maxConcurrentJobsPerPrincipal jobs running being rejected with 429 on a
+// further start, and allowed again once those jobs have been stopped.
+func TestHandleStartJobQuotaExceeded(t *testing.T) {
+	jobworker.Clear()
+	principal := auth.Principal{ID: "quota-operator", Role: "operator"}
+
+	ids := make([]string, 0, maxConcurrentJobsPerPrincipal)
+	for i := 0; i < maxConcurrentJobsPerPrincipal; i++ {
+		id, err := jobworker.StartFor(principal.ID, "sleep", "10")
+		if err != nil {
+			t.Fatalf("failed to start job %d: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+
+	req := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+
+	// Stop the real "sleep 10" processes before clearing jobworker's map out
+	// from under them, so none of them outlive the test.
+	for _, id := range ids {
+		if err := jobworker.Stop(id, time.Second); err != nil {
+			t.Errorf("failed to stop job %s: %v", id, err)
+		}
+	}
+
+	jobworker.Clear()
+	req = withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d after quota freed up", rr.Code, http.StatusOK)
+	}
+}
+
+// TestAuditLogRecordsActionsAndDetectsTamper exercises the audit recorder
+// end-to-end: a successful start, a successful status check, and an auth
+// failure on a stop attempt all land in the log, then tampering with one
+// record is caught by audit.Verify at the correct offset.
+func TestAuditLogRecordsActionsAndDetectsTamper(t *testing.T) {
+	jobworker.Clear()
+
+	path := t.TempDir() + "/audit.log"
+	logger, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	auditLog = logger
+	auditLogPath = path
+	defer func() {
+		logger.Close()
+		auditLog = nil
+		auditLogPath = ""
+	}()
+
+	principal := auth.Principal{ID: "audit-operator", Role: "operator"}
+
+	startReq := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	startRR := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(startRR, startReq)
+	if startRR.Code != http.StatusOK {
+		t.Fatalf("start failed: got status %d", startRR.Code)
+	}
+	var started StartJobResponse
+	if err := json.Unmarshal(startRR.Body.Bytes(), &started); err != nil {
+		t.Fatalf("failed to unmarshal start response: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	statusReq := withPrincipal(httptest.NewRequest("GET", "/jobs/status/"+started.JobID, nil), principal)
+	statusRR := httptest.NewRecorder()
+	http.HandlerFunc(handleGetStatus).ServeHTTP(statusRR, statusReq)
+	if statusRR.Code != http.StatusOK {
+		t.Fatalf("status failed: got status %d", statusRR.Code)
+	}
+
+	authenticators = nil // force the stop attempt below to fail authentication
+	stopHandler := authMiddleware("stop")(http.HandlerFunc(handleStopJob))
+	stopRR := httptest.NewRecorder()
+	stopHandler.ServeHTTP(stopRR, httptest.NewRequest("PUT", "/jobs/stop/"+started.JobID, nil))
+	if stopRR.Code != http.StatusUnauthorized {
+		t.Fatalf("expected stop to be denied, got status %d", stopRR.Code)
+	}
+
+	logger.Close()
+
+	records, err := audit.ReadSince(path, time.Time{})
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d audit records, want 3", len(records))
+	}
+	if records[0].Action != "start" || records[0].Result != auditResultSuccess {
+		t.Errorf("record 0 = %+v, want a successful start", records[0])
+	}
+	if records[1].Action != "status" || records[1].Result != auditResultSuccess {
+		t.Errorf("record 1 = %+v, want a successful status", records[1])
+	}
+	if records[2].Action != "stop" || records[2].Result != auditResultDenied {
+		t.Errorf("record 2 = %+v, want a denied stop", records[2])
+	}
+
+	lines := strings.Split(strings.TrimRight(readFile(t, path), "\n"), "\n")
+	const tamperedLine = 2
+	var tampered map[string]any
+	if err := json.Unmarshal([]byte(lines[tamperedLine-1]), &tampered); err != nil {
+		t.Fatalf("failed to unmarshal line %d: %v", tamperedLine, err)
+	}
+	tampered["result"] = "tampered"
+	rewritten, err := json.Marshal(tampered)
+	if err != nil {
+		t.Fatalf("failed to remarshal line %d: %v", tamperedLine, err)
+	}
+	lines[tamperedLine-1] = string(rewritten)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("failed to rewrite audit log: %v", err)
+	}
+
+	n, err := audit.Verify(path)
+	if err == nil {
+		t.Fatal("expected Verify to detect the tampered record, got nil error")
+	}
+	if n != tamperedLine-1 {
+		t.Errorf("Verify reported %d valid records before the break, want %d", n, tamperedLine-1)
+	}
+}
+
+// TestHandleTailJobMultipleClients starts a job and has two concurrent
+// clients tail it from the beginning, asserting each sees the full output.
+func TestHandleTailJobMultipleClients(t *testing.T) {
+	jobworker.Clear()
+
+	id, err := jobworker.Start("sh", "-c", "for i in 1 2 3; do echo $i; sleep 0.05; done")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	run := func() string {
+		req := httptest.NewRequest("GET", "/jobs/logs/"+id, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handleTailJob).ServeHTTP(rr, req)
+		return rr.Body.String()
+	}
+
+	var out1, out2 string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); out1 = run() }()
+	go func() { defer wg.Done(); out2 = run() }()
+	wg.Wait()
+
+	for _, out := range []string{out1, out2} {
+		if !strings.Contains(out, "data: 1") || !strings.Contains(out, "data: 2") || !strings.Contains(out, "data: 3") {
+			t.Errorf("tail output missing expected lines: %q", out)
+		}
+		if !strings.Contains(out, "event: status") {
+			t.Errorf("tail output missing terminal status event: %q", out)
+		}
+	}
+}
+
+func TestHandleTailJobNotFound(t *testing.T) {
+	jobworker.Clear()
+
+	req := httptest.NewRequest("GET", "/jobs/logs/999", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleTailJob).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+// withMaxConcurrent sets jobworker.MaxConcurrent for the duration of a test
+// and restores the unbounded default (plus a clean scheduler) on cleanup.
+func withMaxConcurrent(t *testing.T, n int) {
+	t.Helper()
+	jobworker.Clear()
+	jobworker.MaxConcurrent = n
+	t.Cleanup(func() {
+		jobworker.MaxConcurrent = 0
+		jobworker.Clear()
+	})
+}
+
+// TestHandleStartJobModeReject covers ?mode=reject returning 429 once
+// MaxConcurrent jobs are already running, rather than queueing the job.
+func TestHandleStartJobModeReject(t *testing.T) {
+	withMaxConcurrent(t, 1)
+	principal := auth.Principal{ID: "reject-operator", Role: "operator"}
+
+	req := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"sleep","args":["1"]}`)), principal)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("first job: got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	req = withPrincipal(httptest.NewRequest("POST", "/jobs/start?mode=reject", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+}
+
+// TestHandleStartJobModeQueue covers the default ?mode=queue behavior: a job
+// submitted beyond the cap is accepted and reported as "Queued" rather than
+// rejected.
+func TestHandleStartJobModeQueue(t *testing.T) {
+	withMaxConcurrent(t, 1)
+	principal := auth.Principal{ID: "queue-operator", Role: "operator"}
+
+	req := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"sleep","args":["1"]}`)), principal)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("first job: got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	req = withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("second job: got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var queued StartJobResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &queued); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	status, err := jobworker.GetStatus(queued.JobID)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status != "Queued" {
+		t.Errorf("second job status = %s, want Queued", status)
+	}
+}
+
+// TestHandleListJobs covers GET /jobs/ reporting every job along with its
+// queue position.
+func TestHandleListJobs(t *testing.T) {
+	withMaxConcurrent(t, 1)
+	principal := auth.Principal{ID: "list-operator", Role: "operator"}
+
+	startReq := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"sleep","args":["1"]}`)), principal)
+	startRR := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(startRR, startReq)
+	queueReq := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	queueRR := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(queueRR, queueReq)
+
+	req := httptest.NewRequest("GET", "/jobs/", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleListJobs).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var jobs []jobworker.JobSummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &jobs); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(jobs))
+	}
+	if jobs[1].QueuePosition != 1 {
+		t.Errorf("second job queue position = %d, want 1", jobs[1].QueuePosition)
+	}
+}
+
+// TestBackpressureMiddlewareSetsHeaders covers every wrapped response
+// advertising the current cap and running-job count.
+func TestBackpressureMiddlewareSetsHeaders(t *testing.T) {
+	withMaxConcurrent(t, 3)
+
+	handler := backpressureMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/jobs/status/1", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-JobWorker-Max-Jobs"); got != "3" {
+		t.Errorf("X-JobWorker-Max-Jobs = %q, want %q", got, "3")
+	}
+	if got := rr.Header().Get("X-JobWorker-Active-Jobs"); got != "0" {
+		t.Errorf("X-JobWorker-Active-Jobs = %q, want %q", got, "0")
+	}
+}
+
+// TestHandleGetStats covers a completed job's resource accounting being
+// retrievable, and an in-flight or unknown job being reported as not found.
+func TestHandleGetStats(t *testing.T) {
+	jobworker.Clear()
+
+	id, err := jobworker.StartForWithLimits("", "echo", jobworker.Limits{MemoryMaxBytes: 64 * 1024 * 1024}, "hello")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/jobs/stats/"+id, nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleGetStats).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var resp StatsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Stats == nil {
+		t.Error("expected non-nil stats for a completed job")
+	}
+
+	req = httptest.NewRequest("GET", "/jobs/stats/999", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handleGetStats).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d for unknown job", rr.Code, http.StatusNotFound)
+	}
+}
+
+// assertProblemDetail decodes rr's body as an RFC 7807 Problem and checks its
+// Content-Type and, when detail is non-empty, its Detail field.
+func assertProblemDetail(t *testing.T, rr *httptest.ResponseRecorder, detail string) {
+	t.Helper()
+	if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want %q", got, "application/problem+json")
+	}
+	var problem Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("failed to unmarshal problem body: %v", err)
+	}
+	if detail != "" && problem.Detail != detail {
+		t.Errorf("problem.Detail = %q, want %q", problem.Detail, detail)
+	}
+}
+
+// withPolicy sets activePolicy for the duration of a test and restores the
+// unrestricted default on cleanup.
+func withPolicy(t *testing.T, pol policy.Policy) {
+	t.Helper()
+	previous := activePolicy
+	activePolicy = pol
+	t.Cleanup(func() { activePolicy = previous })
+}
+
+// TestHandleStartJobPolicyDenied covers a role with no rule in the active
+// RuleSet being rejected before jobworker ever sees the command.
+func TestHandleStartJobPolicyDenied(t *testing.T) {
+	jobworker.Clear()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"roles":{"admin":{"allowed_commands":["echo"]}}}`), 0o644); err != nil {
+		t.Fatalf("failed to write policy config: %v", err)
+	}
+	rs, err := policy.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	withPolicy(t, rs)
+
+	principal := auth.Principal{ID: "viewer-1", Role: "viewer"}
+	req := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d for a role with no configured rule", rr.Code, http.StatusBadRequest)
+	}
+	assertProblemDetail(t, rr, "")
+	if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", got)
+	}
+}
+
+// TestHandleStartJobPolicyAllowedCommand covers a role whose configured rule
+// permits the requested command succeeding normally.
+func TestHandleStartJobPolicyAllowedCommand(t *testing.T) {
+	jobworker.Clear()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"roles":{"operator":{"allowed_commands":["echo"]}}}`), 0o644); err != nil {
+		t.Fatalf("failed to write policy config: %v", err)
+	}
+	rs, err := policy.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	withPolicy(t, rs)
+
+	principal := auth.Principal{ID: "operator-1", Role: "operator"}
+	req := withPrincipal(httptest.NewRequest("POST", "/jobs/start", strings.NewReader(`{"command":"echo","args":["hello"]}`)), principal)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handleStartJob).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d for a policy-allowed command", rr.Code, http.StatusOK)
+	}
+}
+
+// TestHandlePolicyReload covers the admin-only reload endpoint picking up a
+// config file change without restarting the server.
+func TestHandlePolicyReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"roles":{"operator":{"allowed_commands":["echo"]}}}`), 0o644); err != nil {
+		t.Fatalf("failed to write policy config: %v", err)
+	}
+	rs, err := policy.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	withPolicy(t, rs)
+
+	previousRuleSet, previousPath := activeRuleSet, policyConfigPath
+	activeRuleSet, policyConfigPath = rs, path
+	t.Cleanup(func() { activeRuleSet, policyConfigPath = previousRuleSet, previousPath })
+
+	if err := rs.Check("operator", policy.Request{Command: "sleep"}); err == nil {
+		t.Fatal("expected sleep to be rejected before reload")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"roles":{"operator":{"allowed_commands":["echo","sleep"]}}}`), 0o644); err != nil {
+		t.Fatalf("failed to rewrite policy config: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/policy/reload", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlePolicyReload).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if err := rs.Check("operator", policy.Request{Command: "sleep"}); err != nil {
+		t.Errorf("expected sleep to be allowed after reload, got %v", err)
+	}
+}
+
+// TestHandlePolicyReloadWithoutConfigFails covers the reload endpoint
+// reporting an error when the server was never started with --policy-file.
+func TestHandlePolicyReloadWithoutConfigFails(t *testing.T) {
+	previousRuleSet := activeRuleSet
+	activeRuleSet = nil
+	t.Cleanup(func() { activeRuleSet = previousRuleSet })
+
+	req := httptest.NewRequest("POST", "/policy/reload", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlePolicyReload).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
 	}
+	return string(data)
 }
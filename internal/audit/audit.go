@@ -0,0 +1,167 @@
+// Package audit provides a tamper-evident, append-only log of authenticated
+// API actions. Every record is hashed together with the hash of the record
+// before it, so altering or removing a past entry breaks the chain and is
+// detectable by Verify.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record is one audit entry: an authenticated request and its outcome.
+// PrevHash is filled in by Logger.Append from the current chain tip; callers
+// only need to set the remaining fields.
+type Record struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Principal  string    `json:"principal"`
+	Role       string    `json:"role"`
+	Action     string    `json:"action"`
+	JobID      string    `json:"job_id,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	Args       []string  `json:"args,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	Result     string    `json:"result"`
+	Detail     string    `json:"detail,omitempty"`
+	PrevHash   string    `json:"prev_hash"`
+}
+
+// storedRecord is the on-disk shape: a Record plus the SHA-256 hash of its
+// own canonical JSON, chaining it to PrevHash.
+type storedRecord struct {
+	Record
+	Hash string `json:"hash"`
+}
+
+// hashRecord returns the hex-encoded SHA-256 of rec's canonical JSON
+// encoding. Struct field order is fixed by Record's declaration, so this is
+// stable across processes.
+func hashRecord(rec Record) (string, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Logger appends Records to a JSONL file on disk, chaining each one to the
+// hash of the record that precedes it.
+type Logger struct {
+	mu       sync.Mutex
+	f        *os.File
+	lastHash string
+}
+
+// Open opens (creating if necessary) the audit log at path and recovers the
+// current chain tip by replaying any records already there.
+func Open(path string) (*Logger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: opening log: %w", err)
+	}
+
+	lastHash, err := tailHash(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Logger{f: f, lastHash: lastHash}, nil
+}
+
+// tailHash returns the Hash of the last record in f, or "" if f is empty.
+func tailHash(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	defer f.Seek(0, io.SeekEnd)
+
+	var last string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var stored storedRecord
+		if err := json.Unmarshal(line, &stored); err != nil {
+			return "", fmt.Errorf("audit: corrupt log: %w", err)
+		}
+		last = stored.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return last, nil
+}
+
+// Append stamps rec with the current chain tip as PrevHash, writes it to the
+// log, and returns the record actually persisted.
+func (l *Logger) Append(rec Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec.PrevHash = l.lastHash
+	hash, err := hashRecord(rec)
+	if err != nil {
+		return Record{}, err
+	}
+
+	line, err := json.Marshal(storedRecord{Record: rec, Hash: hash})
+	if err != nil {
+		return Record{}, err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return Record{}, err
+	}
+
+	l.lastHash = hash
+	return rec, nil
+}
+
+// Close closes the underlying file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// ReadSince returns every record in the audit log at path with a Timestamp
+// at or after since, in file order.
+func ReadSince(path string, since time.Time) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("audit: opening log: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var stored storedRecord
+		if err := json.Unmarshal(line, &stored); err != nil {
+			return nil, fmt.Errorf("audit: corrupt log: %w", err)
+		}
+		if !stored.Timestamp.Before(since) {
+			records = append(records, stored.Record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
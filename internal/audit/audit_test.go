@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoggerAppendChainsHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer logger.Close()
+
+	records := []Record{
+		{Timestamp: time.Now(), Principal: "alice", Role: "operator", Action: "start", JobID: "1", Command: "echo", Result: "success"},
+		{Timestamp: time.Now(), Principal: "alice", Role: "operator", Action: "status", JobID: "1", Result: "success"},
+		{Timestamp: time.Now(), Principal: "alice", Role: "operator", Action: "stop", JobID: "1", Result: "success"},
+	}
+	for _, rec := range records {
+		if _, err := logger.Append(rec); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	n, err := Verify(path)
+	if err != nil {
+		t.Fatalf("Verify failed on an untampered log: %v", err)
+	}
+	if n != len(records) {
+		t.Errorf("Verify reported %d records, want %d", n, len(records))
+	}
+}
+
+func TestVerifyDetectsTamperedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		rec := Record{Timestamp: time.Now(), Principal: "alice", Role: "operator", Action: "status", JobID: "1", Result: "success"}
+		if _, err := logger.Append(rec); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	logger.Close()
+
+	// Tamper with the third record (1-indexed) in place, leaving its hash
+	// field untouched so the break is detected by content mismatch rather
+	// than by truncation.
+	const tamperedLine = 3
+	lines := readLines(t, path)
+	var tampered map[string]any
+	if err := json.Unmarshal([]byte(lines[tamperedLine-1]), &tampered); err != nil {
+		t.Fatalf("failed to unmarshal line %d: %v", tamperedLine, err)
+	}
+	tampered["result"] = "denied"
+	rewritten, err := json.Marshal(tampered)
+	if err != nil {
+		t.Fatalf("failed to remarshal line %d: %v", tamperedLine, err)
+	}
+	lines[tamperedLine-1] = string(rewritten)
+	writeLines(t, path, lines)
+
+	n, err := Verify(path)
+	if err == nil {
+		t.Fatal("expected Verify to detect the tampered record, got nil error")
+	}
+	if n != tamperedLine-1 {
+		t.Errorf("Verify reported %d valid records before the break, want %d", n, tamperedLine-1)
+	}
+}
+
+func TestReadSinceFiltersByTimestamp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer logger.Close()
+
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	if _, err := logger.Append(Record{Timestamp: older, Principal: "alice", Action: "status", Result: "success"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if _, err := logger.Append(Record{Timestamp: newer, Principal: "alice", Action: "start", Result: "success"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	records, err := ReadSince(path, newer.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("ReadSince failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Action != "start" {
+		t.Errorf("ReadSince = %+v, want only the newer record", records)
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return lines
+}
+
+func writeLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	var out string
+	for _, line := range lines {
+		out += line + "\n"
+	}
+	if err := os.WriteFile(path, []byte(out), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
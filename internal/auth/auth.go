@@ -0,0 +1,43 @@
+// Package auth derives caller identity and role from verified client
+// certificates presented over mTLS, static bearer tokens, or OIDC-issued
+// JWT bearer tokens.
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+)
+
+// Principal identifies an authenticated caller and the role it was granted.
+type Principal struct {
+	ID   string
+	Role string
+}
+
+// RoleMapping maps certificate fields to roles. It is loaded from a config
+// file at server startup.
+type RoleMapping struct {
+	// ByCN maps a certificate's Common Name to a role.
+	ByCN map[string]string `json:"by_cn"`
+	// BySANURI maps a certificate's URI SAN to a role.
+	BySANURI map[string]string `json:"by_san_uri"`
+}
+
+// Resolve derives the Principal for a verified client certificate using m.
+func (m *RoleMapping) Resolve(cert *x509.Certificate) (Principal, error) {
+	if cert == nil {
+		return Principal{}, fmt.Errorf("no client certificate presented")
+	}
+
+	if role, ok := m.ByCN[cert.Subject.CommonName]; ok {
+		return Principal{ID: cert.Subject.CommonName, Role: role}, nil
+	}
+
+	for _, uri := range cert.URIs {
+		if role, ok := m.BySANURI[uri.String()]; ok {
+			return Principal{ID: uri.String(), Role: role}, nil
+		}
+	}
+
+	return Principal{}, fmt.Errorf("no role mapping for certificate %q", cert.Subject.CommonName)
+}
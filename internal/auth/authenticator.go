@@ -0,0 +1,10 @@
+package auth
+
+import "net/http"
+
+// Authenticator resolves the calling Principal from an inbound HTTP request.
+// It returns an error if the request carries no valid credentials for this
+// authenticator.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
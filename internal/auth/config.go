@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadRoleMapping reads a RoleMapping from a JSON config file.
+func LoadRoleMapping(path string) (*RoleMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading role mapping config: %w", err)
+	}
+
+	var m RoleMapping
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing role mapping config: %w", err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRoleMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "role_mapping.json")
+	const doc = `{"by_cn":{"alice":"admin"},"by_san_uri":{"spiffe://jobworker/bob":"operator"}}`
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatalf("failed to write role mapping config: %v", err)
+	}
+
+	m, err := LoadRoleMapping(path)
+	if err != nil {
+		t.Fatalf("LoadRoleMapping failed: %v", err)
+	}
+	if m.ByCN["alice"] != "admin" {
+		t.Errorf("got ByCN[alice] = %q, want %q", m.ByCN["alice"], "admin")
+	}
+	if m.BySANURI["spiffe://jobworker/bob"] != "operator" {
+		t.Errorf("got BySANURI[...] = %q, want %q", m.BySANURI["spiffe://jobworker/bob"], "operator")
+	}
+}
+
+func TestLoadRoleMappingMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "does-not-exist.json")
+
+	if _, err := LoadRoleMapping(path); err == nil {
+		t.Fatal("expected an error for a missing config file")
+	}
+}
+
+func TestLoadRoleMappingMalformedJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "role_mapping.json")
+	if err := os.WriteFile(path, []byte(`{"by_cn": not valid json`), 0o600); err != nil {
+		t.Fatalf("failed to write role mapping config: %v", err)
+	}
+
+	if _, err := LoadRoleMapping(path); err == nil {
+		t.Fatal("expected an error for malformed JSON")
+	}
+}
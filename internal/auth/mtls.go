@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// MTLSAuth derives the caller's Principal from the verified client
+// certificate presented over mTLS, via a RoleMapping.
+type MTLSAuth struct {
+	Mapping *RoleMapping
+}
+
+func (a *MTLSAuth) Authenticate(r *http.Request) (Principal, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return Principal{}, fmt.Errorf("no client certificate presented")
+	}
+	return a.Mapping.Resolve(r.TLS.PeerCertificates[0])
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestOIDCAuthAuthenticate(t *testing.T) {
+	key, err := NewTestRSAKey()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	var issuerURL string
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuerURL, "jwks_uri": issuerURL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{JWK("test-key", &key.PublicKey)}})
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	issuerURL = server.URL
+
+	oidcAuth := &OIDCAuth{
+		Issuer:     issuerURL,
+		Audience:   "jobworker",
+		HTTPClient: server.Client(),
+	}
+
+	tests := []struct {
+		name       string
+		claims     map[string]any
+		wantErr    bool
+		wantRole   string
+		wantSubjec string
+	}{
+		{
+			name: "valid token",
+			claims: map[string]any{
+				"iss":   issuerURL,
+				"aud":   "jobworker",
+				"sub":   "alice",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"roles": "operator",
+			},
+			wantRole:   "operator",
+			wantSubjec: "alice",
+		},
+		{
+			name: "expired token",
+			claims: map[string]any{
+				"iss":   issuerURL,
+				"aud":   "jobworker",
+				"sub":   "alice",
+				"exp":   time.Now().Add(-time.Hour).Unix(),
+				"roles": "operator",
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			claims: map[string]any{
+				"iss":   issuerURL,
+				"aud":   "someone-else",
+				"sub":   "alice",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"roles": "operator",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := SignTestJWT(key, "test-key", tt.claims)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			req, err := http.NewRequest("GET", "/jobs/status/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			principal, err := oidcAuth.Authenticate(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate failed: %v", err)
+			}
+			if principal.Role != tt.wantRole {
+				t.Errorf("got role %q, want %q", principal.Role, tt.wantRole)
+			}
+			if principal.ID != tt.wantSubjec {
+				t.Errorf("got ID %q, want %q", principal.ID, tt.wantSubjec)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthResolveRole(t *testing.T) {
+	oidcAuth := &OIDCAuth{}
+
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		wantRole string
+		wantErr  bool
+	}{
+		{
+			name:     "single string role",
+			claims:   jwt.MapClaims{"roles": "operator"},
+			wantRole: "operator",
+		},
+		{
+			name:     "multi-valued claim picks the most privileged role",
+			claims:   jwt.MapClaims{"roles": []any{"operator", "admin"}},
+			wantRole: "admin",
+		},
+		{
+			name:     "multi-valued claim with no recognized role falls back to the first",
+			claims:   jwt.MapClaims{"roles": []any{"contractor", "guest"}},
+			wantRole: "contractor",
+		},
+		{
+			name:    "missing claim",
+			claims:  jwt.MapClaims{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := oidcAuth.resolveRole(tt.claims)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveRole failed: %v", err)
+			}
+			if role != tt.wantRole {
+				t.Errorf("got role %q, want %q", role, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthAuthenticateMissingBearer(t *testing.T) {
+	oidcAuth := &OIDCAuth{Issuer: "https://issuer.example", Audience: "jobworker"}
+
+	req, err := http.NewRequest("GET", "/jobs/status/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := oidcAuth.Authenticate(req); err == nil {
+		t.Fatal("expected an error for a request with no bearer token")
+	}
+}
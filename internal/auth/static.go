@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// StaticTokenAuth authenticates callers via a fixed bearer-token-to-principal
+// lookup. It exists for local testing and backward compatibility; production
+// deployments should prefer MTLSAuth or OIDCAuth.
+type StaticTokenAuth struct {
+	Tokens map[string]Principal // bearer token -> principal
+}
+
+func (a *StaticTokenAuth) Authenticate(r *http.Request) (Principal, error) {
+	hdr := r.Header.Get("Authorization")
+	if !strings.HasPrefix(hdr, "Bearer ") {
+		return Principal{}, fmt.Errorf("missing bearer token")
+	}
+
+	token := strings.TrimPrefix(hdr, "Bearer ")
+	p, ok := a.Tokens[token]
+	if !ok {
+		return Principal{}, fmt.Errorf("unknown token")
+	}
+	return p, nil
+}
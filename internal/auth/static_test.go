@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStaticTokenAuthAuthenticate(t *testing.T) {
+	a := &StaticTokenAuth{Tokens: map[string]Principal{
+		"admin-token": {ID: "admin-token", Role: "admin"},
+	}}
+
+	req := httptest.NewRequest("GET", "/jobs/status/1", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+
+	p, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if p.Role != "admin" {
+		t.Errorf("got role %q, want %q", p.Role, "admin")
+	}
+	if p.ID != "admin-token" {
+		t.Errorf("got ID %q, want %q", p.ID, "admin-token")
+	}
+}
+
+func TestStaticTokenAuthAuthenticateUnknownToken(t *testing.T) {
+	a := &StaticTokenAuth{Tokens: map[string]Principal{
+		"admin-token": {ID: "admin-token", Role: "admin"},
+	}}
+
+	req := httptest.NewRequest("GET", "/jobs/status/1", nil)
+	req.Header.Set("Authorization", "Bearer bogus-token")
+
+	if _, err := a.Authenticate(req); err == nil {
+		t.Fatal("expected an error for an unrecognized token")
+	}
+}
+
+func TestStaticTokenAuthAuthenticateMissingBearer(t *testing.T) {
+	a := &StaticTokenAuth{Tokens: map[string]Principal{
+		"admin-token": {ID: "admin-token", Role: "admin"},
+	}}
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "no Authorization header", header: ""},
+		{name: "wrong scheme", header: "Basic admin-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/jobs/status/1", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			if _, err := a.Authenticate(req); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
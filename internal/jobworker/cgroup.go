@@ -0,0 +1,207 @@
+package jobworker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Limits caps the resources a job's process (and any children it spawns) may
+// consume. A zero field is left unconstrained, so a caller only sets the
+// dimensions it cares about. CPUWeight and IOWeight follow the cgroup v2
+// convention: a relative share in 1-10000, default 100, not an absolute cap.
+type Limits struct {
+	CPUWeight      int   `json:"cpu_weight,omitempty"`
+	MemoryMaxBytes int64 `json:"memory_max_bytes,omitempty"`
+	IOWeight       int   `json:"io_weight,omitempty"`
+	PidsMax        int   `json:"pids_max,omitempty"`
+}
+
+// ResourceStats is the accounting collected for a job once it exits. Fields
+// are left at zero when cgroups v2 was unavailable and the rlimit fallback
+// was used instead, since rlimits don't expose usage accounting.
+type ResourceStats struct {
+	CPUUsageUsec    uint64 `json:"cpu_usage_usec"`
+	MemoryPeakBytes uint64 `json:"memory_peak_bytes"`
+	IOReadBytes     uint64 `json:"io_read_bytes"`
+	IOWriteBytes    uint64 `json:"io_write_bytes"`
+}
+
+// cgroupRoot is the jobworker's transient cgroup v2 subtree. Each job gets
+// its own directory under it for the duration of its run.
+const cgroupRoot = "/sys/fs/cgroup/jobworker"
+
+func cgroupDir(id string) string {
+	return filepath.Join(cgroupRoot, id)
+}
+
+// cgroupsV2Available reports whether the unified cgroup v2 hierarchy is
+// mounted, which gates whether spawn uses it or falls back to
+// applyRlimitFallback. That fallback calls no setrlimit: the job runs with
+// none of its Limits enforced.
+func cgroupsV2Available() bool {
+	_, err := os.Stat("/sys/fs/cgroup/cgroup.controllers")
+	return err == nil
+}
+
+// setupCgroup creates id's cgroup directory and writes limits into its
+// controller files. Only dimensions with a non-zero value are written, so an
+// unset Limits leaves the job unconstrained beyond cgroup membership itself.
+func setupCgroup(id string, limits Limits) error {
+	dir := cgroupDir(id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating cgroup directory: %w", err)
+	}
+
+	files := map[string]string{}
+	if limits.CPUWeight > 0 {
+		files["cpu.weight"] = strconv.Itoa(limits.CPUWeight)
+	}
+	if limits.MemoryMaxBytes > 0 {
+		files["memory.max"] = strconv.FormatInt(limits.MemoryMaxBytes, 10)
+	}
+	if limits.IOWeight > 0 {
+		files["io.weight"] = strconv.Itoa(limits.IOWeight)
+	}
+	if limits.PidsMax > 0 {
+		files["pids.max"] = strconv.Itoa(limits.PidsMax)
+	}
+
+	for name, value := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// addToCgroup moves pid into id's cgroup. It must run immediately after
+// cmd.Start returns so the job spends as little time as possible running
+// outside its intended limits.
+func addToCgroup(id string, pid int) error {
+	path := filepath.Join(cgroupDir(id), "cgroup.procs")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(pid)), 0o644); err != nil {
+		return fmt.Errorf("writing cgroup.procs: %w", err)
+	}
+	return nil
+}
+
+// removeCgroup deletes id's cgroup directory. It is a no-op if the directory
+// was never created (e.g. setupCgroup failed before spawn used it).
+func removeCgroup(id string) {
+	os.RemoveAll(cgroupDir(id))
+}
+
+// readCgroupStats reads id's cpu.stat, memory.peak, and io.stat into a
+// ResourceStats. It is best-effort: a missing or unreadable file leaves the
+// corresponding fields at zero rather than failing the whole read.
+func readCgroupStats(id string) *ResourceStats {
+	dir := cgroupDir(id)
+	stats := &ResourceStats{}
+
+	if fields, err := readKeyedFile(filepath.Join(dir, "cpu.stat")); err == nil {
+		stats.CPUUsageUsec = fields["usage_usec"]
+	}
+	if peak, err := os.ReadFile(filepath.Join(dir, "memory.peak")); err == nil {
+		stats.MemoryPeakBytes, _ = strconv.ParseUint(strings.TrimSpace(string(peak)), 10, 64)
+	}
+	if rbytes, wbytes, err := readIOStat(filepath.Join(dir, "io.stat")); err == nil {
+		stats.IOReadBytes = rbytes
+		stats.IOWriteBytes = wbytes
+	}
+	return stats
+}
+
+// readKeyedFile parses a cgroup "key value" file, such as cpu.stat, into a
+// map of its fields.
+func readKeyedFile(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fields := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			continue
+		}
+		n, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[parts[0]] = n
+	}
+	return fields, scanner.Err()
+}
+
+// readIOStat parses io.stat, which reports one line per backing device as
+// "<major>:<minor> rbytes=N wbytes=N rios=N wios=N dbytes=N dios=N", and sums
+// rbytes/wbytes across every device the job touched.
+func readIOStat(path string) (rbytes, wbytes uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				rbytes += n
+			case "wbytes":
+				wbytes += n
+			}
+		}
+	}
+	return rbytes, wbytes, scanner.Err()
+}
+
+// rlimitFallbackWarned tracks whether the unenforced-limits warning has
+// already been logged, so a busy server without cgroups v2 doesn't spam its
+// stderr once per job.
+var (
+	rlimitFallbackWarnMu sync.Mutex
+	rlimitFallbackWarned bool
+)
+
+// applyRlimitFallback is the fallback spawn uses when cgroups v2 isn't
+// mounted. setrlimit has no way to scope a limit to a single not-yet-forked
+// child: Getrlimit/Setrlimit only ever act on the calling process, so
+// narrowing RLIMIT_AS/RLIMIT_NPROC here would constrain the whole jobworker
+// server - including the Go runtime's own threads and memory - for as long
+// as one job's limit was in effect, and a single modest limit could starve
+// or crash the server. Rather than risk that, the fallback declines to
+// enforce MemoryMaxBytes/PidsMax at all; the job runs, just without the
+// caps cgroups v2 would have given it. CPUWeight and IOWeight have no
+// rlimit equivalent either way. ResourceStats stays zeroed for a job
+// started this way, since rlimits (even if used) wouldn't give us usage
+// accounting.
+func applyRlimitFallback(limits Limits, start func() error) error {
+	if limits.MemoryMaxBytes > 0 || limits.PidsMax > 0 {
+		rlimitFallbackWarnMu.Lock()
+		if !rlimitFallbackWarned {
+			rlimitFallbackWarned = true
+			fmt.Fprintln(os.Stderr, "jobworker: cgroups v2 unavailable; memory/pids limits are not enforced for jobs on this host")
+		}
+		rlimitFallbackWarnMu.Unlock()
+	}
+	return start()
+}
@@ -3,23 +3,38 @@ package jobworker
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"sync"
+	"syscall"
+	"time"
+
+	"github.com/s-gruneberg/jobWorker/internal/policy"
 )
 
 type Job struct {
-	ID       string   `json:"id"`
-	Command  string   `json:"command"`
-	Args     []string `json:"args"`
-	Status   string   `json:"status"`
-	ExitCode *int     `json:"exit_code,omitempty"`
-	Stdout   string   `json:"stdout"`
-	Stderr   string   `json:"stderr"`
+	ID       string         `json:"id"`
+	OwnerID  string         `json:"owner_id,omitempty"`
+	Command  string         `json:"command"`
+	Args     []string       `json:"args"`
+	Status   string         `json:"status"`
+	ExitCode *int           `json:"exit_code,omitempty"`
+	Stdout   string         `json:"stdout"`
+	Stderr   string         `json:"stderr"`
+	Limits   Limits         `json:"limits"`
+	Stats    *ResourceStats `json:"stats,omitempty"`
 	cmd      *exec.Cmd
 	ctx      context.Context
 	cancel   context.CancelFunc
+	log      *logBroadcaster
+	done     chan struct{}
+	stopping bool
+	env      map[string]string
+	workDir  string
 }
 
 var (
@@ -28,32 +43,263 @@ var (
 	nextID = 1
 )
 
+// LogChunk is one write of stdout or stderr data, tagged with its byte
+// offset in the combined stream so subscribers can resume from where they
+// left off.
+type LogChunk struct {
+	Stream string
+	Data   []byte
+	Offset int64
+}
+
+// logBroadcaster fans a job's combined stdout/stderr out to any number of
+// concurrent subscribers, replaying buffered history to late joiners before
+// tailing new writes.
+type logBroadcaster struct {
+	mu      sync.Mutex
+	history []LogChunk
+	total   int64
+	closed  bool
+	subs    map[int]chan LogChunk
+	nextSub int
+}
+
+func newLogBroadcaster() *logBroadcaster {
+	return &logBroadcaster{subs: make(map[int]chan LogChunk)}
+}
+
+func (b *logBroadcaster) write(stream string, p []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	chunk := LogChunk{Stream: stream, Data: append([]byte(nil), p...), Offset: b.total}
+	b.history = append(b.history, chunk)
+	b.total += int64(len(p))
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- chunk:
+		default:
+			// Slow subscriber - drop the chunk rather than block the job.
+		}
+	}
+}
+
+func (b *logBroadcaster) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, ch := range b.subs {
+		close(ch)
+	}
+	b.subs = nil
+}
+
+// subscribe returns a channel that first replays history from fromOffset,
+// then streams new chunks as they arrive. The channel is closed once the
+// job's output is fully drained, or once ctx is done. Callers must drain the
+// channel until it closes or cancel ctx to avoid leaking the backing
+// goroutine.
+func (b *logBroadcaster) subscribe(ctx context.Context, fromOffset int64) <-chan LogChunk {
+	out := make(chan LogChunk, 64)
+
+	b.mu.Lock()
+	var backlog []LogChunk
+	for _, c := range b.history {
+		if c.Offset+int64(len(c.Data)) > fromOffset {
+			backlog = append(backlog, c)
+		}
+	}
+
+	var live chan LogChunk
+	var id int
+	if !b.closed {
+		id = b.nextSub
+		b.nextSub++
+		live = make(chan LogChunk, 64)
+		b.subs[id] = live
+	}
+	closed := b.closed
+	b.mu.Unlock()
+
+	go func() {
+		defer close(out)
+		defer func() {
+			if live != nil {
+				b.mu.Lock()
+				delete(b.subs, id)
+				b.mu.Unlock()
+			}
+		}()
+
+		for _, c := range backlog {
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if closed || live == nil {
+			return
+		}
+
+		for {
+			select {
+			case c, ok := <-live:
+				if !ok {
+					return
+				}
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// streamWriter persists writes into a per-stream buffer (for GetOutput) and
+// fans them out through the job's log broadcaster (for Subscribe).
+type streamWriter struct {
+	buf    *bytes.Buffer
+	stream string
+	log    *logBroadcaster
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	w.log.write(w.stream, p)
+	return len(p), nil
+}
+
+// Start launches command as an unowned job. It is equivalent to
+// StartFor("", command, args...).
 func Start(command string, args ...string) (string, error) {
+	return StartFor("", command, args...)
+}
+
+// StartFor launches command and attributes the resulting job to owner, so
+// callers can enforce per-owner concurrent-job quotas via CountRunning. It is
+// equivalent to StartForWithLimits(owner, command, Limits{}, args...).
+func StartFor(owner, command string, args ...string) (string, error) {
+	return StartForWithLimits(owner, command, Limits{}, args...)
+}
+
+// StartForWithLimits launches command as StartFor does, additionally
+// constraining it to limits via a transient cgroup v2 subtree. When cgroups
+// v2 isn't mounted, limits.MemoryMaxBytes/PidsMax go unenforced rather than
+// risk the process-wide setrlimit fallback that used to live here - see
+// applyRlimitFallback's doc comment.
+//
+// Neither this, StartFor, nor Start check a Policy - they predate the policy
+// subsystem and existing callers (including most of this package's tests)
+// rely on them remaining unrestricted. Callers that need policy enforcement,
+// in particular the HTTP server, should use StartScheduled, which runs the
+// check unconditionally before anything else.
+func StartForWithLimits(owner, command string, limits Limits, args ...string) (string, error) {
+	return startForWithLimitsEnv(owner, command, limits, nil, "", args...)
+}
+
+// startForWithLimitsEnv is StartForWithLimits plus the environment
+// variables and working directory a Policy may have allowed; StartScheduled
+// uses this when no MaxConcurrent cap is set.
+func startForWithLimitsEnv(owner, command string, limits Limits, env map[string]string, workdir string, args ...string) (string, error) {
 	jobsMu.Lock()
 	id := strconv.Itoa(nextID)
 	nextID++
 	jobsMu.Unlock()
 
+	job, err := spawn(id, owner, command, args, limits, env, workdir, nil)
+	if err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// spawn starts command as job id/owner under limits, registers it as
+// Running, and waits for it to exit in the background. env and workdir, if
+// set, are applied to the child process. If onExit is non-nil it runs once
+// the job's terminal Status/ExitCode and resource Stats have been recorded,
+// after subscribers have been notified - used by the MaxConcurrent scheduler
+// to free the slot it occupied and launch the next queued job, if any.
+func spawn(id, owner, command string, args []string, limits Limits, env map[string]string, workdir string, onExit func()) (*Job, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cmd := exec.CommandContext(ctx, command, args...)
+	// Run the command in its own process group so Stop can signal it and any
+	// children it spawns (shells, pipelines) together via the negated pgid.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if workdir != "" {
+		cmd.Dir = workdir
+	}
+	if len(env) > 0 {
+		merged := os.Environ()
+		for k, v := range env {
+			merged = append(merged, k+"="+v)
+		}
+		cmd.Env = merged
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var stdoutBuf, stderrBuf bytes.Buffer
+	log := newLogBroadcaster()
+	cmd.Stdout = &streamWriter{buf: &stdoutBuf, stream: "stdout", log: log}
+	cmd.Stderr = &streamWriter{buf: &stderrBuf, stream: "stderr", log: log}
 
-	if err := cmd.Start(); err != nil {
+	// Prefer cgroups v2 for limits; it also gives us usage accounting on
+	// exit. When cgroups v2 isn't mounted or the subtree can't be created,
+	// fall back to applyRlimitFallback, which neither enforces limits nor
+	// accounts for usage - see its doc comment for why.
+	cgroupUsed := cgroupsV2Available()
+	if cgroupUsed {
+		if err := setupCgroup(id, limits); err != nil {
+			cgroupUsed = false
+		}
+	}
+
+	var startErr error
+	if cgroupUsed {
+		startErr = cmd.Start()
+	} else {
+		startErr = applyRlimitFallback(limits, cmd.Start)
+	}
+	if startErr != nil {
 		cancel()
-		return "", err
+		if cgroupUsed {
+			removeCgroup(id)
+		}
+		return nil, startErr
+	}
+
+	if cgroupUsed {
+		// Move the child into its cgroup immediately so it spends as little
+		// time as possible running unconstrained.
+		if err := addToCgroup(id, cmd.Process.Pid); err != nil {
+			fmt.Fprintf(os.Stderr, "jobworker: failed to move job %s into its cgroup: %v\n", id, err)
+			removeCgroup(id)
+			cgroupUsed = false
+		}
 	}
 
 	job := &Job{
 		ID:      id,
+		OwnerID: owner,
 		Command: command,
 		Args:    args,
+		Limits:  limits,
 		Status:  "Running",
 		cmd:     cmd,
 		ctx:     ctx,
 		cancel:  cancel,
+		log:     log,
+		done:    make(chan struct{}),
+		env:     env,
+		workDir: workdir,
 	}
 
 	jobsMu.Lock()
@@ -63,23 +309,289 @@ func Start(command string, args ...string) (string, error) {
 	go func() {
 		err := cmd.Wait()
 		jobsMu.Lock()
-		defer jobsMu.Unlock()
+		job.Stdout = stdoutBuf.String()
+		job.Stderr = stderrBuf.String()
 
-		job.Stdout = stdout.String()
-		job.Stderr = stderr.String()
-
-		if err != nil {
+		switch {
+		case job.stopping:
+			job.Status = "Stopped"
+		case err != nil:
 			job.Status = "Failed"
-		} else {
+		default:
 			job.Status = "Succeeded"
 		}
 
 		if exitCode := cmd.ProcessState.ExitCode(); exitCode != -1 {
 			job.ExitCode = &exitCode
 		}
+		jobsMu.Unlock()
+
+		var stats *ResourceStats
+		if cgroupUsed {
+			stats = readCgroupStats(id)
+			removeCgroup(id)
+		} else {
+			// No usage accounting available via rlimits - report the zero
+			// value rather than leaving Stats nil, so GetStats distinguishes
+			// "job hasn't exited yet" from "exited without cgroup stats".
+			stats = &ResourceStats{}
+		}
+		jobsMu.Lock()
+		job.Stats = stats
+		jobsMu.Unlock()
+
+		log.closeAll()
+		close(job.done)
+
+		if onExit != nil {
+			onExit()
+		}
 	}()
 
-	return job.Status, nil
+	return job, nil
+}
+
+// MaxConcurrent caps how many jobs may be running at once, process-wide.
+// Zero (the default) means unlimited; Start, StartFor, and List are
+// unaffected by it - only StartScheduled enforces the cap.
+var MaxConcurrent int
+
+// ErrAtCapacity is returned by StartScheduled when mode is "reject" and
+// MaxConcurrent jobs are already running.
+var ErrAtCapacity = errors.New("jobworker: at capacity")
+
+// ErrPolicyDenied wraps the error StartScheduled returns when pol.Check
+// rejects an invocation, so callers can distinguish a policy denial from
+// other start failures with errors.Is.
+var ErrPolicyDenied = errors.New("jobworker: policy denied")
+
+var (
+	schedMu   sync.Mutex
+	runningCt int
+	queue     []string // job IDs waiting for a slot, in FIFO order
+)
+
+// StartScheduled launches command subject to MaxConcurrent, limits, and
+// pol. pol.Check runs first, against role, and rejects the invocation before
+// anything else happens; a nil pol imposes no restriction (policy.AllowAll),
+// for direct callers (library embedders, this package's own tests) that
+// deliberately opt out of policy enforcement. That is distinct from, and
+// does not excuse, the HTTP server's own default - cmd/server's activePolicy
+// is never left nil and defaults to policy.DenyAll so a deployment started
+// without --policy-file fails closed. With the cap unset (MaxConcurrent <=
+// 0) it otherwise behaves exactly like
+// StartForWithLimits. Once MaxConcurrent jobs are running, mode "reject"
+// fails fast with ErrAtCapacity; any other mode (in particular "queue")
+// registers the job as "Queued" and launches it once a running slot frees
+// up, in FIFO order. env and workdir, once allowed by pol, are applied to
+// the child process.
+func StartScheduled(owner, role, command, mode string, limits Limits, pol policy.Policy, env map[string]string, workdir string, args ...string) (string, error) {
+	if pol == nil {
+		pol = policy.AllowAll{}
+	}
+	if err := pol.Check(role, policy.Request{Command: command, Args: args, Env: env, WorkDir: workdir}); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrPolicyDenied, err)
+	}
+
+	if MaxConcurrent <= 0 {
+		return startForWithLimitsEnv(owner, command, limits, env, workdir, args...)
+	}
+
+	jobsMu.Lock()
+	id := strconv.Itoa(nextID)
+	nextID++
+	jobsMu.Unlock()
+
+	schedMu.Lock()
+	if runningCt < MaxConcurrent {
+		runningCt++
+		schedMu.Unlock()
+		if _, err := spawn(id, owner, command, args, limits, env, workdir, releaseSlot); err != nil {
+			releaseSlot()
+			return "", err
+		}
+		return id, nil
+	}
+
+	if mode == "reject" {
+		schedMu.Unlock()
+		return "", ErrAtCapacity
+	}
+
+	queue = append(queue, id)
+	schedMu.Unlock()
+
+	job := &Job{ID: id, OwnerID: owner, Command: command, Args: args, Limits: limits, Status: "Queued", done: make(chan struct{}), env: env, workDir: workdir}
+	jobsMu.Lock()
+	jobs[id] = job
+	jobsMu.Unlock()
+
+	return id, nil
+}
+
+// releaseSlot frees the running slot a scheduled job held and, if another
+// job is waiting, launches it. It is safe to call from spawn's exit
+// goroutine and from StartScheduled's own error path.
+func releaseSlot() {
+	schedMu.Lock()
+	if len(queue) == 0 {
+		runningCt--
+		schedMu.Unlock()
+		return
+	}
+	nextJobID := queue[0]
+	queue = queue[1:]
+	schedMu.Unlock()
+
+	jobsMu.Lock()
+	job, ok := jobs[nextJobID]
+	jobsMu.Unlock()
+	if !ok {
+		schedMu.Lock()
+		runningCt--
+		schedMu.Unlock()
+		return
+	}
+
+	if _, err := spawn(job.ID, job.OwnerID, job.Command, job.Args, job.Limits, job.env, job.workDir, releaseSlot); err != nil {
+		jobsMu.Lock()
+		job.Status = "Failed"
+		jobsMu.Unlock()
+		close(job.done)
+		releaseSlot()
+	}
+}
+
+// JobSummary is one List() entry: a job's public fields plus its 1-based
+// position in the scheduler queue (0 for jobs that are running or already
+// finished).
+type JobSummary struct {
+	Job
+	QueuePosition int `json:"queue_position,omitempty"`
+}
+
+// List returns every tracked job, ordered by ID, each annotated with its
+// queue position if it is still Queued.
+func List() []JobSummary {
+	schedMu.Lock()
+	positions := make(map[string]int, len(queue))
+	for i, id := range queue {
+		positions[id] = i + 1
+	}
+	schedMu.Unlock()
+
+	jobsMu.RLock()
+	summaries := make([]JobSummary, 0, len(jobs))
+	for _, job := range jobs {
+		summaries = append(summaries, JobSummary{Job: *job, QueuePosition: positions[job.ID]})
+	}
+	jobsMu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		a, _ := strconv.Atoi(summaries[i].ID)
+		b, _ := strconv.Atoi(summaries[j].ID)
+		return a < b
+	})
+	return summaries
+}
+
+// ActiveCount returns the number of jobs currently in the "Running" status,
+// for the X-JobWorker-Active-Jobs backpressure header.
+func ActiveCount() int {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+
+	n := 0
+	for _, job := range jobs {
+		if job.Status == "Running" {
+			n++
+		}
+	}
+	return n
+}
+
+// Stop sends SIGTERM to the job's process group, waits up to graceTimeout
+// for it to exit, then escalates to SIGKILL. The job's status is set to
+// "Stopped" (distinct from "Failed") once it has exited. Signaling the
+// negated pid targets the whole process group Start placed the job in, so
+// children it spawned (shells, pipelines) are reaped along with it.
+func Stop(id string, graceTimeout time.Duration) error {
+	jobsMu.Lock()
+	job, ok := jobs[id]
+	if !ok {
+		jobsMu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	if job.Status != "Running" {
+		jobsMu.Unlock()
+		return fmt.Errorf("job is not running")
+	}
+	job.stopping = true
+	pgid := job.cmd.Process.Pid
+	jobsMu.Unlock()
+
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("sending SIGTERM: %w", err)
+	}
+
+	select {
+	case <-job.done:
+		return nil
+	case <-time.After(graceTimeout):
+	}
+
+	if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
+		return fmt.Errorf("sending SIGKILL: %w", err)
+	}
+
+	<-job.done
+	return nil
+}
+
+// Subscribe streams a job's combined stdout/stderr from fromOffset, replaying
+// buffered history before tailing live output. The returned cancel func must
+// be called once the caller is done consuming the channel.
+func Subscribe(id string, fromOffset int64) (<-chan LogChunk, context.CancelFunc, error) {
+	jobsMu.RLock()
+	job, ok := jobs[id]
+	jobsMu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("job not found")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return job.log.subscribe(ctx, fromOffset), cancel, nil
+}
+
+// Tail streams a job's combined stdout/stderr from the beginning, replaying
+// buffered history before tailing live output, until ctx is cancelled or the
+// job's output is fully drained. Unlike Subscribe, lifetime is governed
+// entirely by ctx (e.g. an HTTP request's context), so there is no separate
+// cancel func to call.
+func Tail(id string, ctx context.Context) (<-chan LogChunk, error) {
+	jobsMu.RLock()
+	job, ok := jobs[id]
+	jobsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("job not found")
+	}
+
+	return job.log.subscribe(ctx, 0), nil
+}
+
+// CountRunning returns the number of jobs owned by owner that are currently
+// in the "Running" status, for enforcing per-owner concurrency quotas.
+func CountRunning(owner string) int {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+
+	n := 0
+	for _, job := range jobs {
+		if job.OwnerID == owner && job.Status == "Running" {
+			n++
+		}
+	}
+	return n
 }
 
 func GetOutput(id string) (string, string, error) {
@@ -103,3 +615,39 @@ func GetStatus(id string) (string, error) {
 	}
 	return job.Status, nil
 }
+
+// GetStats returns the resource accounting captured for id. It errors if id
+// is unknown or hasn't exited yet - stats are only collected once a job's
+// cgroup is torn down.
+func GetStats(id string) (*ResourceStats, error) {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+
+	job, ok := jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job not found")
+	}
+	if job.Stats == nil {
+		return nil, fmt.Errorf("stats not available yet")
+	}
+	return job.Stats, nil
+}
+
+// clear resets all tracked jobs. It exists for test isolation between cases
+// that assert on job IDs.
+func clear() {
+	jobsMu.Lock()
+	jobs = make(map[string]*Job)
+	nextID = 1
+	jobsMu.Unlock()
+
+	schedMu.Lock()
+	runningCt = 0
+	queue = nil
+	schedMu.Unlock()
+}
+
+// Clear resets all tracked jobs. Exported for use by other packages' tests.
+func Clear() {
+	clear()
+}
@@ -1,6 +1,11 @@
 package jobworker
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
 	"testing"
 	"time"
 )
@@ -63,7 +68,7 @@ func TestStopJob(t *testing.T) {
 		t.Fatalf("Failed to start job: %v", err)
 	}
 
-	err = Stop(id)
+	err = Stop(id, time.Second)
 	if err != nil {
 		t.Fatalf("Failed to stop job: %v", err)
 	}
@@ -79,6 +84,69 @@ func TestStopJob(t *testing.T) {
 	}
 }
 
+// TestStopJobEscalatesToSIGKILL covers a process that ignores SIGTERM: Stop
+// should fall back to SIGKILL after graceTimeout rather than hanging.
+func TestStopJobEscalatesToSIGKILL(t *testing.T) {
+	clear()
+
+	id, err := Start("sh", "-c", "trap '' TERM; sleep 10")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // let the trap install before signaling
+
+	start := time.Now()
+	if err := Stop(id, 200*time.Millisecond); err != nil {
+		t.Fatalf("Failed to stop job: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Stop returned after %v, expected to wait out the grace period", elapsed)
+	}
+
+	status, err := GetStatus(id)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if status != "Stopped" {
+		t.Errorf("Expected status %s, got %s", "Stopped", status)
+	}
+}
+
+// TestStopJobReapsProcessGroup starts a job that forks a child sleep process
+// and asserts Stop's group-wide signal takes the child down too.
+func TestStopJobReapsProcessGroup(t *testing.T) {
+	clear()
+
+	id, err := Start("sh", "-c", "sleep 10 & wait")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := Stop(id, time.Second); err != nil {
+		t.Fatalf("Failed to stop job: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if out, err := exec.Command("pgrep", "-f", "sleep 10").CombinedOutput(); err == nil {
+		t.Errorf("expected no leftover 'sleep 10' process, pgrep found: %s", out)
+	}
+}
+
+func TestStopNotRunningJobFails(t *testing.T) {
+	clear()
+
+	id, err := Start("echo", "hello")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := Stop(id, time.Second); err == nil {
+		t.Error("expected Stop to fail for a job that is no longer running")
+	}
+}
+
 func TestGetOutput(t *testing.T) {
 	clear()
 
@@ -102,3 +170,312 @@ func TestGetOutput(t *testing.T) {
 		t.Errorf("Expected empty stderr, got '%s'", stderr)
 	}
 }
+
+func TestSubscribeMultipleConcurrentSubscribers(t *testing.T) {
+	clear()
+
+	id, err := Start("sh", "-c", "for i in 1 2 3; do echo $i; sleep 0.05; done")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	ch1, cancel1, err := Subscribe(id, 0)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer cancel1()
+
+	ch2, cancel2, err := Subscribe(id, 0)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer cancel2()
+
+	collect := func(ch <-chan LogChunk) string {
+		var out bytes.Buffer
+		for chunk := range ch {
+			out.Write(chunk.Data)
+		}
+		return out.String()
+	}
+
+	var got1, got2 string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); got1 = collect(ch1) }()
+	go func() { defer wg.Done(); got2 = collect(ch2) }()
+	wg.Wait()
+
+	const want = "1\n2\n3\n"
+	if got1 != want {
+		t.Errorf("subscriber 1 got %q, want %q", got1, want)
+	}
+	if got2 != want {
+		t.Errorf("subscriber 2 got %q, want %q", got2, want)
+	}
+}
+
+func TestSubscribeResumeFromOffset(t *testing.T) {
+	clear()
+
+	id, err := Start("sh", "-c", "for i in 1 2 3; do echo $i; sleep 0.05; done")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	stdout, _, err := GetOutput(id)
+	if err != nil {
+		t.Fatalf("Failed to get output: %v", err)
+	}
+
+	resumeFrom := int64(len("1\n"))
+	ch, cancel, err := Subscribe(id, resumeFrom)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer cancel()
+
+	var out bytes.Buffer
+	for chunk := range ch {
+		out.Write(chunk.Data)
+	}
+
+	if out.String() != stdout[resumeFrom:] {
+		t.Errorf("resumed stream = %q, want %q", out.String(), stdout[resumeFrom:])
+	}
+}
+
+func TestTailStreamsFullHistoryToMultipleClients(t *testing.T) {
+	clear()
+
+	id, err := Start("sh", "-c", "for i in 1 2 3; do echo $i; sleep 0.05; done")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	ch1, err := Tail(id, ctx1)
+	if err != nil {
+		t.Fatalf("Failed to tail: %v", err)
+	}
+	ch2, err := Tail(id, ctx2)
+	if err != nil {
+		t.Fatalf("Failed to tail: %v", err)
+	}
+
+	collect := func(ch <-chan LogChunk) string {
+		var out bytes.Buffer
+		for chunk := range ch {
+			out.Write(chunk.Data)
+		}
+		return out.String()
+	}
+
+	var got1, got2 string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); got1 = collect(ch1) }()
+	go func() { defer wg.Done(); got2 = collect(ch2) }()
+	wg.Wait()
+
+	const want = "1\n2\n3\n"
+	if got1 != want {
+		t.Errorf("client 1 got %q, want %q", got1, want)
+	}
+	if got2 != want {
+		t.Errorf("client 2 got %q, want %q", got2, want)
+	}
+}
+
+func TestTailJobNotFound(t *testing.T) {
+	clear()
+
+	if _, err := Tail("missing", context.Background()); err == nil {
+		t.Fatal("expected an error for an unknown job ID")
+	}
+}
+
+func TestCountRunning(t *testing.T) {
+	clear()
+
+	for i := 0; i < 3; i++ {
+		if _, err := StartFor("alice", "sleep", "1"); err != nil {
+			t.Fatalf("Failed to start job: %v", err)
+		}
+	}
+	if _, err := StartFor("bob", "sleep", "1"); err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	if n := CountRunning("alice"); n != 3 {
+		t.Errorf("CountRunning(alice) = %d, want 3", n)
+	}
+	if n := CountRunning("bob"); n != 1 {
+		t.Errorf("CountRunning(bob) = %d, want 1", n)
+	}
+	if n := CountRunning("carol"); n != 0 {
+		t.Errorf("CountRunning(carol) = %d, want 0", n)
+	}
+}
+
+// withMaxConcurrent sets MaxConcurrent for the duration of a test and
+// restores the unbounded default (plus a clean scheduler) on cleanup.
+func withMaxConcurrent(t *testing.T, n int) {
+	t.Helper()
+	clear()
+	MaxConcurrent = n
+	t.Cleanup(func() {
+		MaxConcurrent = 0
+		clear()
+	})
+}
+
+func TestStartScheduledQueuesBeyondCap(t *testing.T) {
+	withMaxConcurrent(t, 1)
+
+	id1, err := StartScheduled("alice", "", "sleep", "queue", Limits{}, nil, nil, "", "0.2")
+	if err != nil {
+		t.Fatalf("Failed to start job 1: %v", err)
+	}
+	id2, err := StartScheduled("alice", "", "echo", "queue", Limits{}, nil, nil, "", "hello")
+	if err != nil {
+		t.Fatalf("Failed to start job 2: %v", err)
+	}
+
+	status1, err := GetStatus(id1)
+	if err != nil {
+		t.Fatalf("Failed to get status of job 1: %v", err)
+	}
+	if status1 != "Running" {
+		t.Errorf("job 1 status = %s, want Running", status1)
+	}
+
+	status2, err := GetStatus(id2)
+	if err != nil {
+		t.Fatalf("Failed to get status of job 2: %v", err)
+	}
+	if status2 != "Queued" {
+		t.Errorf("job 2 status = %s, want Queued", status2)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	status2, err = GetStatus(id2)
+	if err != nil {
+		t.Fatalf("Failed to get status of job 2: %v", err)
+	}
+	if status2 != "Succeeded" {
+		t.Errorf("job 2 status after job 1 finished = %s, want Succeeded", status2)
+	}
+}
+
+func TestStartScheduledRejectsBeyondCap(t *testing.T) {
+	withMaxConcurrent(t, 1)
+
+	if _, err := StartScheduled("alice", "", "sleep", "reject", Limits{}, nil, nil, "", "1"); err != nil {
+		t.Fatalf("Failed to start job 1: %v", err)
+	}
+
+	_, err := StartScheduled("alice", "", "echo", "reject", Limits{}, nil, nil, "", "hello")
+	if !errors.Is(err, ErrAtCapacity) {
+		t.Errorf("got error %v, want ErrAtCapacity", err)
+	}
+}
+
+func TestListReportsQueuePositions(t *testing.T) {
+	withMaxConcurrent(t, 1)
+
+	runningID, err := StartScheduled("alice", "", "sleep", "queue", Limits{}, nil, nil, "", "1")
+	if err != nil {
+		t.Fatalf("Failed to start running job: %v", err)
+	}
+	queuedID1, err := StartScheduled("alice", "", "echo", "queue", Limits{}, nil, nil, "", "1")
+	if err != nil {
+		t.Fatalf("Failed to queue job 1: %v", err)
+	}
+	queuedID2, err := StartScheduled("alice", "", "echo", "queue", Limits{}, nil, nil, "", "2")
+	if err != nil {
+		t.Fatalf("Failed to queue job 2: %v", err)
+	}
+
+	positions := make(map[string]int)
+	for _, summary := range List() {
+		positions[summary.ID] = summary.QueuePosition
+	}
+
+	if positions[runningID] != 0 {
+		t.Errorf("running job queue position = %d, want 0", positions[runningID])
+	}
+	if positions[queuedID1] != 1 {
+		t.Errorf("first queued job position = %d, want 1", positions[queuedID1])
+	}
+	if positions[queuedID2] != 2 {
+		t.Errorf("second queued job position = %d, want 2", positions[queuedID2])
+	}
+}
+
+// TestStartForWithLimitsSucceedsAndCollectsStats covers a job started with
+// Limits running to completion and GetStats returning accounting for it,
+// whether or not cgroups v2 was actually available to enforce the limits in
+// this environment.
+func TestStartForWithLimitsSucceedsAndCollectsStats(t *testing.T) {
+	clear()
+
+	limits := Limits{CPUWeight: 50, MemoryMaxBytes: 64 * 1024 * 1024, IOWeight: 50, PidsMax: 16}
+	id, err := StartForWithLimits("alice", "echo", limits, "hello")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	status, err := waitForTerminal(id)
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if status != "Succeeded" {
+		t.Fatalf("job status = %s, want Succeeded", status)
+	}
+
+	if _, err := GetStats(id); err != nil {
+		t.Errorf("GetStats failed after job exited: %v", err)
+	}
+}
+
+// TestGetStatsBeforeExitFails covers GetStats reporting an error for a job
+// that's still running, since accounting is only collected on exit.
+func TestGetStatsBeforeExitFails(t *testing.T) {
+	clear()
+
+	id, err := StartForWithLimits("alice", "sleep", Limits{MemoryMaxBytes: 64 * 1024 * 1024}, "1")
+	if err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+
+	if _, err := GetStats(id); err == nil {
+		t.Error("expected GetStats to fail for a still-running job")
+	}
+}
+
+// waitForTerminal polls GetStatus until the job reaches a terminal status or
+// the poll budget is exhausted.
+func waitForTerminal(id string) (string, error) {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, err := GetStatus(id)
+		if err != nil {
+			return "", err
+		}
+		if status == "Succeeded" || status == "Failed" || status == "Stopped" {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return status, nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,65 @@
+// Package policy decides which commands a role may ask jobworker to run,
+// and under what constraints - allowed arguments, disallowed environment
+// variables, and permitted working directories.
+package policy
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Request describes a single job invocation to be checked against a Policy.
+type Request struct {
+	Command string
+	Args    []string
+	Env     map[string]string
+	WorkDir string
+}
+
+// Policy decides whether role may execute req, returning a descriptive error
+// if not.
+type Policy interface {
+	Check(role string, req Request) error
+}
+
+// AllowAll imposes no restrictions. jobworker.StartScheduled uses it when
+// given a nil Policy, for callers that opt out of policy enforcement rather
+// than silently inheriting DenyAll.
+type AllowAll struct{}
+
+// Check always succeeds.
+func (AllowAll) Check(role string, req Request) error { return nil }
+
+// DenyAll rejects every invocation. It is the policy a misconfigured or
+// unconfigured deployment should fail closed to, rather than open.
+type DenyAll struct{}
+
+// Check always fails.
+func (DenyAll) Check(role string, req Request) error {
+	return fmt.Errorf("policy: no rules configured, denying %q for role %q", req.Command, role)
+}
+
+// Rule is one role's set of constraints, loaded from a Config.
+type Rule struct {
+	// AllowedCommands lists the binaries this role may execute. A command
+	// not in this list is always rejected.
+	AllowedCommands []string `json:"allowed_commands"`
+	// ArgPattern, if set, is a regexp every argument must fully match.
+	ArgPattern string `json:"arg_pattern,omitempty"`
+	// DisallowedEnv lists environment variable names this role's jobs may
+	// not set.
+	DisallowedEnv []string `json:"disallowed_env,omitempty"`
+	// AllowedWorkDirs restricts which working directories this role's jobs
+	// may run in. Empty means any directory is permitted.
+	AllowedWorkDirs []string `json:"allowed_work_dirs,omitempty"`
+	// MaxArgs caps the number of arguments a job may be started with. Zero
+	// means unlimited.
+	MaxArgs int `json:"max_args,omitempty"`
+
+	argRe *regexp.Regexp
+}
+
+// Config is the on-disk policy configuration: one Rule per role.
+type Config struct {
+	Roles map[string]Rule `json:"roles"`
+}
@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// RuleSet is a config-driven Policy: each role's Rule is checked
+// independently, and a role with no configured Rule is denied. It supports
+// hot reload via Reload, so the server can pick up config changes without a
+// restart.
+type RuleSet struct {
+	mu    sync.RWMutex
+	roles map[string]Rule
+}
+
+// Load reads a Config from a JSON file and builds a RuleSet from it.
+func Load(path string) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if err := rs.Reload(path); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Reload re-reads path and atomically replaces rs's rules, compiling each
+// role's ArgPattern. An error leaves rs's existing rules in place.
+func (rs *RuleSet) Reload(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading policy config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("parsing policy config: %w", err)
+	}
+
+	roles := make(map[string]Rule, len(cfg.Roles))
+	for role, rule := range cfg.Roles {
+		if rule.ArgPattern != "" {
+			re, err := regexp.Compile("^(?:" + rule.ArgPattern + ")$")
+			if err != nil {
+				return fmt.Errorf("compiling arg_pattern for role %q: %w", role, err)
+			}
+			rule.argRe = re
+		}
+		roles[role] = rule
+	}
+
+	rs.mu.Lock()
+	rs.roles = roles
+	rs.mu.Unlock()
+	return nil
+}
+
+// Check enforces role's Rule against req. A role with no configured Rule is
+// denied, matching DenyAll's fail-closed default.
+func (rs *RuleSet) Check(role string, req Request) error {
+	rs.mu.RLock()
+	rule, ok := rs.roles[role]
+	rs.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("policy: no rule configured for role %q", role)
+	}
+
+	if !contains(rule.AllowedCommands, req.Command) {
+		return fmt.Errorf("policy: role %q may not execute %q", role, req.Command)
+	}
+	if rule.MaxArgs > 0 && len(req.Args) > rule.MaxArgs {
+		return fmt.Errorf("policy: role %q exceeded max_args (%d > %d)", role, len(req.Args), rule.MaxArgs)
+	}
+	if rule.argRe != nil {
+		for _, arg := range req.Args {
+			if !rule.argRe.MatchString(arg) {
+				return fmt.Errorf("policy: argument %q does not match the allowed pattern for role %q", arg, role)
+			}
+		}
+	}
+	for _, denied := range rule.DisallowedEnv {
+		if _, set := req.Env[denied]; set {
+			return fmt.Errorf("policy: role %q may not set environment variable %q", role, denied)
+		}
+	}
+	if len(rule.AllowedWorkDirs) > 0 && !contains(rule.AllowedWorkDirs, req.WorkDir) {
+		return fmt.Errorf("policy: working directory %q is not permitted for role %q", req.WorkDir, role)
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, cfg string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatalf("failed to write policy config: %v", err)
+	}
+	return path
+}
+
+func TestRuleSetRejectsUnlistedCommand(t *testing.T) {
+	path := writeConfig(t, `{"roles":{"operator":{"allowed_commands":["echo"]}}}`)
+	rs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := rs.Check("operator", Request{Command: "rm"}); err == nil {
+		t.Error("expected rm to be rejected for operator")
+	}
+	if err := rs.Check("operator", Request{Command: "echo"}); err != nil {
+		t.Errorf("expected echo to be allowed for operator, got %v", err)
+	}
+}
+
+func TestRuleSetRejectsUnknownRole(t *testing.T) {
+	path := writeConfig(t, `{"roles":{"operator":{"allowed_commands":["echo"]}}}`)
+	rs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := rs.Check("viewer", Request{Command: "echo"}); err == nil {
+		t.Error("expected a role with no configured rule to be denied")
+	}
+}
+
+func TestRuleSetEnforcesArgPattern(t *testing.T) {
+	path := writeConfig(t, `{"roles":{"operator":{"allowed_commands":["echo"],"arg_pattern":"^[a-z]+$"}}}`)
+	rs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := rs.Check("operator", Request{Command: "echo", Args: []string{"hello"}}); err != nil {
+		t.Errorf("expected lowercase arg to be allowed, got %v", err)
+	}
+	if err := rs.Check("operator", Request{Command: "echo", Args: []string{"; rm -rf /"}}); err == nil {
+		t.Error("expected a shell-metacharacter argument to be rejected")
+	}
+}
+
+func TestRuleSetEnforcesMaxArgs(t *testing.T) {
+	path := writeConfig(t, `{"roles":{"operator":{"allowed_commands":["echo"],"max_args":1}}}`)
+	rs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := rs.Check("operator", Request{Command: "echo", Args: []string{"one"}}); err != nil {
+		t.Errorf("expected one arg to be allowed, got %v", err)
+	}
+	if err := rs.Check("operator", Request{Command: "echo", Args: []string{"one", "two"}}); err == nil {
+		t.Error("expected two args to exceed max_args")
+	}
+}
+
+func TestRuleSetEnforcesDisallowedEnv(t *testing.T) {
+	path := writeConfig(t, `{"roles":{"operator":{"allowed_commands":["echo"],"disallowed_env":["LD_PRELOAD"]}}}`)
+	rs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := rs.Check("operator", Request{Command: "echo", Env: map[string]string{"LD_PRELOAD": "/evil.so"}}); err == nil {
+		t.Error("expected LD_PRELOAD to be rejected")
+	}
+	if err := rs.Check("operator", Request{Command: "echo", Env: map[string]string{"PATH": "/usr/bin"}}); err != nil {
+		t.Errorf("expected PATH to be allowed, got %v", err)
+	}
+}
+
+func TestRuleSetEnforcesAllowedWorkDirs(t *testing.T) {
+	path := writeConfig(t, `{"roles":{"operator":{"allowed_commands":["echo"],"allowed_work_dirs":["/srv/jobs"]}}}`)
+	rs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := rs.Check("operator", Request{Command: "echo", WorkDir: "/srv/jobs"}); err != nil {
+		t.Errorf("expected /srv/jobs to be allowed, got %v", err)
+	}
+	if err := rs.Check("operator", Request{Command: "echo", WorkDir: "/etc"}); err == nil {
+		t.Error("expected /etc to be rejected")
+	}
+	if err := rs.Check("operator", Request{Command: "echo", WorkDir: ""}); err == nil {
+		t.Error("expected an unset WorkDir to be rejected once allowed_work_dirs is non-empty")
+	}
+}
+
+func TestRuleSetReload(t *testing.T) {
+	path := writeConfig(t, `{"roles":{"operator":{"allowed_commands":["echo"]}}}`)
+	rs, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := rs.Check("operator", Request{Command: "sleep"}); err == nil {
+		t.Fatal("expected sleep to be rejected before reload")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"roles":{"operator":{"allowed_commands":["echo","sleep"]}}}`), 0o644); err != nil {
+		t.Fatalf("failed to rewrite policy config: %v", err)
+	}
+	if err := rs.Reload(path); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if err := rs.Check("operator", Request{Command: "sleep"}); err != nil {
+		t.Errorf("expected sleep to be allowed after reload, got %v", err)
+	}
+}
+
+func TestDenyAllRejectsEverything(t *testing.T) {
+	if err := (DenyAll{}).Check("admin", Request{Command: "echo"}); err == nil {
+		t.Error("expected DenyAll to reject every invocation")
+	}
+}
+
+func TestAllowAllAllowsEverything(t *testing.T) {
+	if err := (AllowAll{}).Check("admin", Request{Command: "rm", Args: []string{"-rf", "/"}}); err != nil {
+		t.Errorf("expected AllowAll to allow every invocation, got %v", err)
+	}
+}
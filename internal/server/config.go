@@ -0,0 +1,80 @@
+// Package server builds the TLS configuration the HTTP server terminates
+// connections with: mutual TLS by default, restricted to modern protocol
+// versions and cipher suites.
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// Config describes the server's TLS transport. CertFile and KeyFile are
+// always required; CAFile is only required when client certificates should
+// be verified (mTLS) - leaving it empty produces a TLS config that still
+// terminates HTTPS but accepts any client, for deployments that rely on the
+// static bearer-token fallback instead.
+type Config struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// TLSMinVersion is the minimum negotiated protocol version, in the form
+	// crypto/tls.Config expects (tls.VersionTLS13, ...). Zero defaults to
+	// TLS 1.3.
+	TLSMinVersion uint16
+	// AllowMissingClientCert relaxes client-certificate enforcement from
+	// RequireAndVerifyClientCert to VerifyClientCertIfGiven when CAFile is
+	// set, so a deployment that also enables the static bearer-token
+	// authenticator can let clients without a certificate through the TLS
+	// handshake and fall back to a bearer token in authenticate(). A client
+	// that does present a certificate still has it verified against CAFile.
+	AllowMissingClientCert bool
+}
+
+// restrictedCipherSuites is used whenever TLSMinVersion is lowered to allow
+// TLS 1.2 negotiation; TLS 1.3's suites aren't configurable in crypto/tls and
+// are always AEAD, so this list only ever constrains the TLS 1.2 fallback.
+var restrictedCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+// TLSConfig builds a *tls.Config from c. If CAFile is set, client
+// certificates are verified against it; the verified peer certificate of
+// each connection can then be mapped to a role by auth.MTLSAuth. Certificates
+// are required unless AllowMissingClientCert is also set, in which case a
+// client may omit one entirely and authenticate another way instead.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	minVersion := c.TLSMinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS13
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: restrictedCipherSuites,
+	}
+
+	if c.CAFile == "" {
+		return cfg, nil
+	}
+
+	caCert, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA file: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse CA certificate")
+	}
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if c.AllowMissingClientCert {
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	cfg.ClientCAs = caPool
+
+	return cfg, nil
+}
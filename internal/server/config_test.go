@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCACert generates a throwaway self-signed CA certificate, PEM
+// encoded, for exercising TLSConfig's CAFile handling without a real CA on
+// disk.
+func newTestCACert() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func TestTLSConfigDefaultsToTLS13(t *testing.T) {
+	cfg := Config{}
+
+	tlsCfg, err := cfg.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+	if tlsCfg.MinVersion != tls.VersionTLS13 {
+		t.Errorf("MinVersion = %x, want TLS 1.3", tlsCfg.MinVersion)
+	}
+	if tlsCfg.ClientAuth != tls.NoClientCert {
+		t.Errorf("ClientAuth = %v, want NoClientCert when CAFile is unset", tlsCfg.ClientAuth)
+	}
+}
+
+func TestTLSConfigRequiresClientCertsWhenCAFileSet(t *testing.T) {
+	ca, err := newTestCACert()
+	if err != nil {
+		t.Fatalf("failed to build test CA cert: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, ca, 0o600); err != nil {
+		t.Fatalf("failed to write CA cert: %v", err)
+	}
+
+	cfg := Config{CAFile: path, TLSMinVersion: tls.VersionTLS12}
+	tlsCfg, err := cfg.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+	if tlsCfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("ClientAuth = %v, want RequireAndVerifyClientCert", tlsCfg.ClientAuth)
+	}
+	if tlsCfg.ClientCAs == nil {
+		t.Error("expected ClientCAs pool to be populated")
+	}
+	if tlsCfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want the overridden TLS 1.2", tlsCfg.MinVersion)
+	}
+}
+
+func TestTLSConfigAllowsMissingClientCertWhenConfigured(t *testing.T) {
+	ca, err := newTestCACert()
+	if err != nil {
+		t.Fatalf("failed to build test CA cert: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, ca, 0o600); err != nil {
+		t.Fatalf("failed to write CA cert: %v", err)
+	}
+
+	cfg := Config{CAFile: path, AllowMissingClientCert: true}
+	tlsCfg, err := cfg.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+	if tlsCfg.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Errorf("ClientAuth = %v, want VerifyClientCertIfGiven", tlsCfg.ClientAuth)
+	}
+	if tlsCfg.ClientCAs == nil {
+		t.Error("expected ClientCAs pool to still be populated")
+	}
+}
+
+func TestTLSConfigInvalidCAFile(t *testing.T) {
+	cfg := Config{CAFile: filepath.Join(t.TempDir(), "missing.pem")}
+
+	if _, err := cfg.TLSConfig(); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}